@@ -0,0 +1,333 @@
+// Package cmder generates commands, i.e. typed builders, from structs.
+//
+// It is the library behind the go-cmder tool:
+//
+//	files, err := cmder.Generate(ctx, cmder.Config{
+//		Commands: []cmder.Command{
+//			{Struct: "Struct", Name: "CreateStructCmd", Mutable: true},
+//		},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//
+//	return cmder.Write(files, os.Stdout)
+package cmder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+	"github.com/donatorsky/go-cmder/internal/generator"
+	"github.com/donatorsky/go-cmder/internal/utils"
+)
+
+// StdoutOut is the output file name making the command be written to the standard output.
+const StdoutOut = generator.StdoutOut
+
+// outputFileMode is the mode of created output files.
+const outputFileMode fs.FileMode = 0644
+
+type (
+	// Command describes a command to generate from a struct.
+	Command = generator.Command
+	// Constructor describes a constructor of the command, with the fields it sets.
+	Constructor = generator.Constructor
+	// EmbeddedMode tells how fields of an embedded struct are generated.
+	EmbeddedMode = generator.EmbeddedMode
+	// Diagnostic is a problem found while generating commands, with its position and severity.
+	Diagnostic = diagnostic.Diagnostic
+	// Severity tells whether a diagnostic is an error or a warning.
+	Severity = diagnostic.Severity
+	// Struct is a struct declared in a package, commands can be generated from.
+	Struct = generator.Struct
+	// StructField is a field of a Struct.
+	StructField = generator.StructField
+)
+
+const (
+	SeverityError   = diagnostic.SeverityError
+	SeverityWarning = diagnostic.SeverityWarning
+)
+
+const (
+	EmbeddedKeep    = generator.EmbeddedKeep
+	EmbeddedFlatten = generator.EmbeddedFlatten
+	EmbeddedSkip    = generator.EmbeddedSkip
+)
+
+const (
+	CollisionsError  = generator.CollisionsError
+	CollisionsSuffix = generator.CollisionsSuffix
+	CollisionsPrefix = generator.CollisionsPrefix
+)
+
+// ParseConstructor parses "Name[:field,field]" constructor specification.
+func ParseConstructor(value string) (Constructor, error) {
+	return generator.ParseConstructor(value)
+}
+
+// ParseEmbedded parses "Field:mode" embedded field's mode specification.
+func ParseEmbedded(value string) (name string, mode EmbeddedMode, _ error) {
+	return generator.ParseEmbedded(value)
+}
+
+// ParseRename parses "Field:Name" field's rename specification.
+func ParseRename(value string) (path string, name string, _ error) {
+	return generator.ParseRename(value)
+}
+
+// ParseNested parses "Field[:CommandName]" nested command specification.
+func ParseNested(value string) (path string, name string, _ error) {
+	return generator.ParseNested(value)
+}
+
+// Generate generates config's commands, and commands discovered in its packages, and returns their sources
+// by output paths. Nothing is written. Commands written to StdoutOut are concatenated under StdoutOut key.
+//
+// Problems of all commands are joined together, sources of the commands generated successfully are returned
+// regardless. Use Diagnostics to list them with their positions: when all of them are warnings, all sources are returned.
+func Generate(ctx context.Context, config Config) (map[string][]byte, error) {
+	g, err := newGenerator(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := config.Commands
+
+	var discoveryErr error
+
+	if len(config.Packages) > 0 {
+		var discovered []Command
+
+		discovered, discoveryErr = g.Discover(config.Packages...)
+		commands = append(commands[:len(commands):len(commands)], discovered...)
+	}
+
+	files, err := g.GenerateAll(commands)
+
+	return files, errors.Join(discoveryErr, err)
+}
+
+// Structs lists structs declared in config's packages, the current package by default, sorted by name within every package.
+func Structs(ctx context.Context, config Config) ([]Struct, error) {
+	g, err := newGenerator(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Structs(packagesOrCurrent(config.Packages)...)
+}
+
+// GeneratedFiles lists sorted paths of files generated by go-cmder, recognized by their header,
+// in config's packages, the current package by default.
+func GeneratedFiles(ctx context.Context, config Config) ([]string, error) {
+	g, err := newGenerator(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.GeneratedFiles(packagesOrCurrent(config.Packages)...)
+}
+
+// Clean removes files listed by GeneratedFiles and returns their paths.
+func Clean(ctx context.Context, config Config) ([]string, error) {
+	files, err := GeneratedFiles(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := make([]string, 0, len(files))
+
+	var errs []error
+
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		removed = append(removed, file)
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+func newGenerator(ctx context.Context, config Config) (*generator.Generator, error) {
+	dir := config.Dir
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("could not get current working directory: %w", err)
+		}
+
+		dir = cwd
+	}
+
+	templates := config.Templates
+	if templates != "" && !filepath.IsAbs(templates) {
+		templates = filepath.Join(dir, templates)
+	}
+
+	return generator.New(dir, generator.WithContext(ctx), generator.WithTemplatesDir(templates))
+}
+
+func packagesOrCurrent(patterns []string) []string {
+	if len(patterns) == 0 {
+		return []string{"."}
+	}
+
+	return patterns
+}
+
+// Diagnostics lists problems joined in given error, e.g. returned by Generate, with their positions and severities.
+// Errors other than diagnostics are listed as errors without a position. Diagnostics of StaleError carry its diff.
+func Diagnostics(err error) []*Diagnostic {
+	diagnostics := diagnostic.List(err, token.Position{})
+
+	for _, d := range diagnostics {
+		var staleErr *StaleError
+		if errors.As(d, &staleErr) {
+			d.Diff = staleErr.Diff
+		}
+	}
+
+	return diagnostics
+}
+
+// HasErrors reports whether any of given diagnostics is an error rather than a warning.
+func HasErrors(diagnostics []*Diagnostic) bool {
+	return diagnostic.HasErrors(diagnostics)
+}
+
+// Write writes generated files, in the order of their paths. StdoutOut file is written to stdout.
+// Each file is replaced atomically, through a temporary file in the same directory, and is left
+// untouched if its content is already up to date.
+func Write(files map[string][]byte, stdout io.Writer) error {
+	var errs []error
+
+	for _, path := range sortedPaths(files) {
+		if path == StdoutOut {
+			if _, err := stdout.Write(files[path]); err != nil {
+				errs = append(errs, fmt.Errorf("failed to write command to stdout: %w", err))
+			}
+
+			continue
+		}
+
+		if err := writeFile(path, files[path]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// StaleError reports a generated command differing from its existing output file.
+type StaleError struct {
+	Path string
+	// Diff is the unified diff from the existing file to the generated command.
+	Diff string
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("%s is out of date, run go generate", e.Path)
+}
+
+// Check compares generated files with the existing ones, in the order of their paths. Files differing
+// from the existing ones are reported with StaleError. StdoutOut file is not checked.
+func Check(files map[string][]byte) error {
+	var errs []error
+
+	for _, path := range sortedPaths(files) {
+		if path == StdoutOut {
+			continue
+		}
+
+		if err := checkFile(path, files[path]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// checkFile compares generated command with the existing output file.
+func checkFile(path string, generated []byte) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read command output file: %w", err)
+	}
+
+	diff := utils.UnifiedDiff(path+" (existing)", path+" (generated)", string(existing), string(generated))
+	if diff != "" {
+		return &StaleError{
+			Path: path,
+			Diff: diff,
+		}
+	}
+
+	return nil
+}
+
+// writeFile atomically replaces the output file with generated command, through a temporary file
+// in the same directory. The file is left untouched if its content is already up to date.
+func writeFile(path string, generated []byte) error {
+	existing, err := os.ReadFile(path)
+	if err == nil && bytes.Equal(existing, generated) {
+		return nil
+	}
+
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read command output file: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary command output file: %w", err)
+	}
+
+	tempPath := file.Name()
+
+	_, err = file.Write(generated)
+	if err == nil {
+		err = file.Chmod(outputFileMode)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tempPath)
+
+		return fmt.Errorf("failed to write command output file: %w", err)
+	}
+
+	return nil
+}
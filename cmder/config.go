@@ -0,0 +1,55 @@
+package cmder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultConfigFile = ".cmder.yaml"
+
+// Config describes commands to generate.
+type Config struct {
+	// Dir is the directory packages are resolved against. Defaults to the current working directory.
+	Dir string `yaml:"-"`
+	// Templates is a directory with templates overriding the default ones. A relative path is resolved against Dir.
+	Templates string `yaml:"templates"`
+	// Commands lists commands to generate.
+	Commands []Command `yaml:"commands"`
+	// Packages lists package patterns to discover //cmder:command directives in.
+	Packages []string `yaml:"-"`
+}
+
+// LoadConfig loads config file. Packages and templates directory are resolved against the config file's directory.
+func LoadConfig(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var config Config
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	config.Dir, err = filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Templates != "" && !filepath.IsAbs(config.Templates) {
+		config.Templates = filepath.Join(config.Dir, config.Templates)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,90 @@
+package cmder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+)
+
+// Explanation describes a generated file: its imports, declared types with their resolved field types,
+// and signatures of the constructors and methods.
+type Explanation struct {
+	Path    string
+	Package string
+	// Imports are import specs as written in the file, e.g. `foo "example.com/foo"`.
+	Imports []string
+	// Types are type declarations, e.g. "type CreateStructCmd struct {...}".
+	Types []string
+	// Funcs are signatures of functions and methods, e.g. "func (cmd CreateStructCmd) Foo() string".
+	Funcs []string
+}
+
+// Explain describes generated files, e.g. returned by Generate, in the order of their paths.
+func Explain(files map[string][]byte) ([]Explanation, error) {
+	var (
+		explanations []Explanation
+		errs         []error
+	)
+
+	for _, path := range sortedPaths(files) {
+		explanation, err := explain(path, files[path])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+
+			continue
+		}
+
+		explanations = append(explanations, explanation)
+	}
+
+	return explanations, errors.Join(errs...)
+}
+
+func explain(path string, src []byte) (Explanation, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	explanation := Explanation{
+		Path:    path,
+		Package: file.Name.Name,
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.ImportSpec:
+					explanation.Imports = append(explanation.Imports, printNode(fset, s))
+				case *ast.TypeSpec:
+					explanation.Types = append(explanation.Types, "type "+printNode(fset, s))
+				}
+			}
+
+		case *ast.FuncDecl:
+			signature := *d
+			signature.Doc = nil
+			signature.Body = nil
+
+			explanation.Funcs = append(explanation.Funcs, printNode(fset, &signature))
+		}
+	}
+
+	return explanation, nil
+}
+
+func printNode(fset *token.FileSet, node ast.Node) string {
+	var b bytes.Buffer
+
+	_ = (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&b, fset, node)
+
+	return b.String()
+}
@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+//go:generate go-cmder .
+//cmder:command InternalStructCmd constructor=default sorted
+//cmder:command MutableInternalStructCmd mutable exclude=File constructor=WithFoo:Foo,Time
 type InternalStruct struct {
 	Foo  string
 	Time time.Time
@@ -2,7 +2,10 @@ module github.com/donatorsky/go-cmder
 
 go 1.20
 
-require golang.org/x/tools v0.13.0
+require (
+	golang.org/x/tools v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	golang.org/x/mod v0.12.0 // indirect
@@ -0,0 +1,167 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found while generating commands, located at the offending
+// field, directive or generated code when known.
+type Diagnostic struct {
+	Position token.Position
+	Severity Severity
+	Message  string
+	// Diff is the unified diff of a stale generated file to its up-to-date version, if any.
+	Diff string
+	err  error
+}
+
+// Errorf returns an error diagnostic at given position.
+func Errorf(position token.Position, format string, args ...any) *Diagnostic {
+	err := fmt.Errorf(format, args...)
+
+	return &Diagnostic{
+		Position: position,
+		Severity: SeverityError,
+		Message:  err.Error(),
+		err:      errors.Unwrap(err),
+	}
+}
+
+// Warningf returns a warning diagnostic at given position.
+func Warningf(position token.Position, format string, args ...any) *Diagnostic {
+	d := Errorf(position, format, args...)
+	d.Severity = SeverityWarning
+
+	return d
+}
+
+// Error returns the diagnostic's message, so wrapping errors read naturally. Use String to get it with the position.
+func (d *Diagnostic) Error() string {
+	return d.Message
+}
+
+func (d *Diagnostic) Unwrap() error {
+	return d.err
+}
+
+// String formats the diagnostic as "file:line:col: message", so editors can jump to it.
+// Warnings are marked with "warning: " prefix of the message.
+func (d *Diagnostic) String() string {
+	message := d.Message
+	if d.Severity == SeverityWarning {
+		message = "warning: " + message
+	}
+
+	if d.Position.Filename == "" && !d.Position.IsValid() {
+		return message
+	}
+
+	return fmt.Sprintf("%s: %s", d.Position, message)
+}
+
+func (d *Diagnostic) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		File     string   `json:"file,omitempty"`
+		Line     int      `json:"line,omitempty"`
+		Column   int      `json:"column,omitempty"`
+		Severity Severity `json:"severity"`
+		Message  string   `json:"message"`
+		Diff     string   `json:"diff,omitempty"`
+	}{
+		File:     d.Position.Filename,
+		Line:     d.Position.Line,
+		Column:   d.Position.Column,
+		Severity: d.Severity,
+		Message:  d.Message,
+		Diff:     d.Diff,
+	})
+}
+
+// List flattens joined errors into diagnostics. An error wrapping a diagnostic takes its position
+// and severity, but keeps its own message. Other errors are reported at given fallback position.
+func List(err error, fallback token.Position) []*Diagnostic {
+	if err == nil {
+		return nil
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var diagnostics []*Diagnostic
+
+		for _, err := range joined.Unwrap() {
+			diagnostics = append(diagnostics, List(err, fallback)...)
+		}
+
+		return diagnostics
+	}
+
+	d := &Diagnostic{
+		Position: fallback,
+		Severity: SeverityError,
+		Message:  err.Error(),
+		err:      err,
+	}
+
+	var wrapped *Diagnostic
+	if errors.As(err, &wrapped) {
+		d.Severity = wrapped.Severity
+
+		if wrapped.Position.IsValid() || wrapped.Position.Filename != "" {
+			d.Position = wrapped.Position
+		}
+	}
+
+	return []*Diagnostic{d}
+}
+
+// HasErrors reports whether any of given diagnostics is an error rather than a warning.
+func HasErrors(diagnostics []*Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity != SeverityWarning {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParsePosition parses "file:line:col" position, as reported by go/packages. Missing line and column are left zero.
+func ParsePosition(value string) token.Position {
+	var position token.Position
+
+	for _, field := range []*int{&position.Column, &position.Line} {
+		i := strings.LastIndex(value, ":")
+		if i < 0 {
+			break
+		}
+
+		n, err := strconv.Atoi(value[i+1:])
+		if err != nil {
+			break
+		}
+
+		*field = n
+		value = value[:i]
+	}
+
+	if position.Line == 0 && position.Column != 0 {
+		position.Line, position.Column = position.Column, 0
+	}
+
+	if value != "-" {
+		position.Filename = value
+	}
+
+	return position
+}
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/donatorsky/go-cmder/internal/template"
+	"golang.org/x/tools/go/packages"
+)
+
+// GeneratedFiles lists sorted paths of Go files starting with template.GeneratedHeader in packages matching given patterns,
+// including files excluded by build constraints. Packages are not type-checked, so broken generated files are found too.
+func (g *Generator) GeneratedFiles(patterns ...string) ([]string, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Context: g.loader.config.Context,
+		Mode:    packages.NeedName | packages.NeedFiles,
+		Dir:     g.dir,
+	}, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load packages: %w", err)
+	}
+
+	var (
+		files []string
+		errs  []error
+	)
+
+	seen := map[string]bool{}
+
+	for _, pkg := range pkgs {
+		for _, file := range append(pkg.GoFiles[:len(pkg.GoFiles):len(pkg.GoFiles)], pkg.IgnoredFiles...) {
+			if seen[file] {
+				continue
+			}
+
+			seen[file] = true
+
+			generated, err := isGenerated(file)
+			if err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			if generated {
+				files = append(files, file)
+			}
+		}
+	}
+
+	sort.Strings(files)
+
+	return files, errors.Join(errs...)
+}
+
+// isGenerated reports whether the file's first line is template.GeneratedHeader.
+func isGenerated(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+
+	return scanner.Text() == template.GeneratedHeader, nil
+}
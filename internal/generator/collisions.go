@@ -0,0 +1,183 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+	"github.com/donatorsky/go-cmder/internal/template"
+)
+
+const (
+	// CollisionsError fails generation when fields' accessors collide. This is the default.
+	CollisionsError = "error"
+	// CollisionsSuffix appends the affix to accessor names of colliding fields until they are unique.
+	CollisionsSuffix = "suffix"
+	// CollisionsPrefix prepends the affix to accessor names of colliding fields until they are unique.
+	CollisionsPrefix = "prefix"
+
+	defaultCollisionsAffix = "Field"
+)
+
+// collisions returns the strategy of resolving accessors' collisions and its affix.
+func (c *Command) collisions() (strategy string, affix string) {
+	strategy, affix, _ = strings.Cut(c.Collisions, ":")
+
+	if strategy == "" {
+		strategy = CollisionsError
+	}
+
+	if affix == "" {
+		affix = defaultCollisionsAffix
+	}
+
+	return strategy, affix
+}
+
+// validateCollisions checks the strategy of resolving accessors' collisions.
+func (c *Command) validateCollisions() error {
+	strategy, affix := c.collisions()
+
+	switch strategy {
+	case CollisionsError:
+		if strings.Contains(c.Collisions, ":") {
+			return fmt.Errorf("invalid collisions strategy %q: error strategy takes no affix", c.Collisions)
+		}
+
+	case CollisionsSuffix, CollisionsPrefix:
+		if !token.IsIdentifier(affix) || (strategy == CollisionsPrefix && !token.IsExported(affix)) {
+			return fmt.Errorf("invalid collisions strategy %q: affix must be an identifier, exported for prefix strategy", c.Collisions)
+		}
+
+	default:
+		return fmt.Errorf("invalid collisions strategy %q, expected one of: error, suffix[:Affix], prefix[:Affix]", c.Collisions)
+	}
+
+	return nil
+}
+
+// resolveCollisions detects fields whose accessor names differ only in case, and fields whose methods clash
+// with methods of other fields, with methods declared by the additional command templates, or with Validate method
+// called by validate constructors, e.g. a field named Validate. Depending on the command's strategy, the later field's
+// accessor name is affixed until it is unique, or every conflicting pair is reported.
+func (p *plan) resolveCollisions(node *commandNode) error {
+	strategy, affix := p.command.collisions()
+
+	var (
+		accessors = map[string]*structField{}
+		methods   = map[string]*structField{}
+		errs      []error
+	)
+
+	reservedMethods := map[string]string{}
+
+	for method := range p.templateMethods(node) {
+		reservedMethods[method] = fmt.Sprintf("%s method declared by additional command templates", method)
+	}
+
+	// Constructors are generated for the main command only.
+	if len(node.path) == 0 && p.command.validates() {
+		reservedMethods[validateMethod] = fmt.Sprintf("%s() error method called by validate constructors", validateMethod)
+	}
+
+	for _, field := range node.fields {
+		for {
+			conflict := p.collision(node, field, accessors, methods, reservedMethods)
+			if conflict == "" {
+				break
+			}
+
+			if strategy == CollisionsError {
+				errs = append(errs, diagnostic.Errorf(
+					p.sourcePkg.Fset.Position(field.field.Pos()),
+					"field %s conflicts with %s, set collisions strategy or rename one of them",
+					p.selector(node.path, field), conflict,
+				))
+
+				break
+			}
+
+			if strategy == CollisionsSuffix {
+				field.accessor += affix
+			} else {
+				field.accessor = affix + field.accessor
+			}
+		}
+
+		accessors[strings.ToLower(field.accessor)] = field
+
+		for _, method := range p.accessorMethods(field) {
+			methods[method] = field
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// collision describes what the field's accessors collide with, or returns an empty string.
+// reservedMethods describes methods that are not generated for fields by their names.
+func (p *plan) collision(node *commandNode, field *structField, accessors, methods map[string]*structField, reservedMethods map[string]string) string {
+	if other, ok := accessors[strings.ToLower(field.accessor)]; ok {
+		if other.accessor == field.accessor {
+			return fmt.Sprintf("field %s: both have accessor name %s", p.selector(node.path, other), field.accessor)
+		}
+
+		return fmt.Sprintf("field %s: accessor names %s and %s differ only in case", p.selector(node.path, other), other.accessor, field.accessor)
+	}
+
+	for _, method := range p.accessorMethods(field) {
+		if other, ok := methods[method]; ok {
+			return fmt.Sprintf("field %s: both generate %s method", p.selector(node.path, other), method)
+		}
+
+		if reserved, ok := reservedMethods[method]; ok {
+			return reserved
+		}
+	}
+
+	return ""
+}
+
+func (p *plan) accessorMethods(field *structField) []string {
+	return []string{
+		p.command.getterPrefix() + field.accessor,
+		p.command.setterPrefix() + field.accessor,
+		p.command.haserPrefix() + field.accessor,
+	}
+}
+
+// templateMethods lists methods declared by the additional command templates executed for the command
+// without fields. No methods are listed when the templates fail without fields, outputs that do not parse are skipped.
+func (p *plan) templateMethods(node *commandNode) map[string]bool {
+	outputs, err := p.tpl.ExecuteExtraCommandTemplates(&template.CommandData{
+		PackageName: p.targetPkg.Name,
+		CommandName: node.name,
+		TypeParams:  p.typeParams,
+		TypeArgs:    p.typeArgs,
+		Source:      node.source,
+	})
+	if err != nil {
+		return nil
+	}
+
+	methods := map[string]bool{}
+
+	for _, output := range outputs {
+		file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+output, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv != nil {
+				methods[funcDecl.Name.Name] = true
+			}
+		}
+	}
+
+	return methods
+}
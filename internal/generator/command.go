@@ -0,0 +1,352 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+)
+
+type Command struct {
+	Struct            string `yaml:"struct"`
+	SourcePkg         string `yaml:"source_pkg"`
+	Name              string `yaml:"name"`
+	Package           string `yaml:"package"`
+	Out               string `yaml:"out"`
+	Mutable           bool   `yaml:"mutable"`
+	IncludeUnexported bool   `yaml:"include_unexported"`
+	Sorted            bool   `yaml:"sorted"`
+	// Include and Exclude list comma-separated field selectors, e.g. "Foo", "*At,Address.Zip", "type:func" or "tag:json=-".
+	Include      []string      `yaml:"include"`
+	Exclude      []string      `yaml:"exclude"`
+	Constructors []Constructor `yaml:"constructors"`
+	// Embedded maps embedded fields' paths, e.g. "Base" or "Base.Audit" for Audit embedded in flattened Base, to their modes.
+	Embedded map[string]EmbeddedMode `yaml:"embedded"`
+	// Nested maps struct-typed fields' paths, e.g. "Address" or "Address.Geo", to the names of nested commands
+	// to generate for them. Empty name defaults to the parent command's name followed by the field's name.
+	Nested map[string]string `yaml:"nested"`
+	// Renames maps fields' paths to the names used in their accessors, e.g. "Str" for "string" field generates Str() and SetStr().
+	Renames map[string]string `yaml:"rename"`
+	// Initialisms makes accessors' names upper-case initialisms in fields' names, e.g. ID() instead of Id() for "id" field.
+	Initialisms bool `yaml:"initialisms"`
+	// GetterPrefix is the prefix of getters' names, e.g. "Get". Empty by default.
+	GetterPrefix string `yaml:"getter_prefix"`
+	// SetterPrefix is the prefix of setters' names, e.g. "With". Defaults to "Set".
+	SetterPrefix string `yaml:"setter_prefix"`
+	// HaserPrefix is the prefix of hasers' names, e.g. "IsSet". Defaults to "Has".
+	HaserPrefix string `yaml:"haser_prefix"`
+	// Collisions is the strategy of resolving collisions of fields' accessors: "error" (default), "suffix[:Affix]"
+	// or "prefix[:Affix]", e.g. "suffix:Field" generates StringField() for "string" field colliding with "String" one.
+	Collisions string `yaml:"collisions"`
+	// Position is the position of the //cmder:command directive declaring the command, if any.
+	// Problems not related to a particular field are reported at it.
+	Position token.Position `yaml:"-"`
+}
+
+func (c *Command) String() string {
+	return fmt.Sprintf("%s (%s)", c.Name, c.Struct)
+}
+
+// Invocation returns go-cmder command line generating the command, with flags in a fixed order.
+// templatesDir is the directory of custom templates, relative to the command's package directory, if any.
+func (c *Command) Invocation(templatesDir string) string {
+	args := []string{"go-cmder"}
+
+	for _, flag := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"-mutable", c.Mutable},
+		{"-include-unexported", c.IncludeUnexported},
+		{"-sorted", c.Sorted},
+		{"-initialisms", c.Initialisms},
+	} {
+		if flag.enabled {
+			args = append(args, flag.name)
+		}
+	}
+
+	// Command written to stdout is recorded as written to its default output file, so rerunning it recreates the file.
+	if c.Out != "" && c.Out != defaultOut(c.Name) && c.Out != StdoutOut {
+		args = append(args, "-out", c.Out)
+	}
+
+	if c.SourcePkg != "" {
+		args = append(args, "-source-pkg", c.SourcePkg)
+	}
+
+	if templatesDir != "" {
+		args = append(args, "-templates", templatesDir)
+	}
+
+	if c.Collisions != "" && c.Collisions != CollisionsError {
+		args = append(args, "-collisions", c.Collisions)
+	}
+
+	for _, prefix := range []struct {
+		flag         string
+		value        string
+		defaultValue string
+	}{
+		{"-getter-prefix", c.GetterPrefix, ""},
+		{"-setter-prefix", c.SetterPrefix, defaultSetterPrefix},
+		{"-haser-prefix", c.HaserPrefix, defaultHaserPrefix},
+	} {
+		if prefix.value != "" && prefix.value != prefix.defaultValue {
+			args = append(args, prefix.flag, prefix.value)
+		}
+	}
+
+	for _, name := range c.Exclude {
+		args = append(args, "-exclude", name)
+	}
+
+	for _, name := range c.Include {
+		args = append(args, "-include", name)
+	}
+
+	for _, name := range sortedKeys(c.Embedded) {
+		args = append(args, "-embedded", fmt.Sprintf("%s:%s", name, c.Embedded[name]))
+	}
+
+	for _, path := range sortedKeys(c.Nested) {
+		if c.Nested[path] == "" {
+			args = append(args, "-nested", path)
+		} else {
+			args = append(args, "-nested", fmt.Sprintf("%s:%s", path, c.Nested[path]))
+		}
+	}
+
+	for _, path := range sortedKeys(c.Renames) {
+		args = append(args, "-rename", fmt.Sprintf("%s:%s", path, c.Renames[path]))
+	}
+
+	for _, constructor := range c.Constructors {
+		args = append(args, "-constructor", constructor.String())
+	}
+
+	args = append(args, c.Struct, c.Name)
+
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \t\n\"'`$") {
+			args[i] = strconv.Quote(arg)
+		}
+	}
+
+	return strings.Join(args, " ")
+}
+
+// diagnostics lists problems of generating the command, prefixed with the command's name. Problems
+// not located at a particular field are reported at the command's position.
+func (c *Command) diagnostics(err error) []error {
+	diagnostics := diagnostic.List(err, c.Position)
+	errs := make([]error, 0, len(diagnostics))
+
+	for _, d := range diagnostics {
+		d.Message = fmt.Sprintf("%s: %s", c, d.Message)
+		errs = append(errs, d)
+	}
+
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Constructor is a constructor of the command, specified as "name[:fields[:flags]]", e.g. "WithFoo:Foo,Bar",
+// "All:*,-Internal" or "Validated:*:validate,ptr".
+type Constructor struct {
+	Name string
+	// Params lists fields to take as the constructor's parameters. "*" stands for all the command's fields,
+	// and may be followed by fields to leave out, prefixed with "-", e.g. "-Foo".
+	Params []string
+	// Validate makes the constructor return (Cmd, error): the result of the command's Validate() error method.
+	Validate bool
+	// Ptr makes the constructor take values for pointer fields and store pointers to them.
+	Ptr bool
+}
+
+const (
+	allConstructorParams = "*"
+
+	constructorFlagValidate = "validate"
+	constructorFlagPtr      = "ptr"
+
+	// validateMethod is the command's method called by validate constructors.
+	validateMethod = "Validate"
+)
+
+// ParseConstructor parses "name[:fields[:flags]]" constructor specification.
+func ParseConstructor(value string) (c Constructor, _ error) {
+	parts := strings.SplitN(value, ":", 3)
+
+	c.Name = parts[0]
+	if c.Name == "" {
+		return c, fmt.Errorf("invalid constructor %q: missing name, expected name[:fields[:flags]], e.g. WithFoo:Foo,Bar", value)
+	}
+
+	if !strings.EqualFold(c.Name, "default") && !token.IsIdentifier("New"+c.Name) {
+		return c, fmt.Errorf("invalid constructor %q: name %q cannot be used in an identifier", value, c.Name)
+	}
+
+	if len(parts) > 1 && parts[1] != "" {
+		c.Params = strings.Split(parts[1], ",")
+
+		seen := map[string]bool{}
+
+		for i, param := range c.Params {
+			name := strings.TrimPrefix(param, "-")
+
+			switch {
+			case param == allConstructorParams:
+				if i > 0 {
+					return c, fmt.Errorf("invalid constructor %q: * must be the first field", value)
+				}
+
+				continue
+
+			case name != param && c.Params[0] != allConstructorParams:
+				return c, fmt.Errorf("invalid constructor %q: fields can be left out with %s only after *, e.g. *,%s", value, param, param)
+
+			case name == param && c.Params[0] == allConstructorParams:
+				return c, fmt.Errorf("invalid constructor %q: field %s is already included by *, prefix it with - to leave it out", value, param)
+
+			case !token.IsIdentifier(name):
+				return c, fmt.Errorf("invalid constructor %q: %q is not a field name", value, param)
+
+			case seen[name]:
+				return c, fmt.Errorf("invalid constructor %q: field %s is given more than once", value, name)
+			}
+
+			seen[name] = true
+		}
+	}
+
+	if len(parts) > 2 {
+		for _, flag := range strings.Split(parts[2], ",") {
+			switch flag {
+			case constructorFlagValidate:
+				c.Validate = true
+			case constructorFlagPtr:
+				c.Ptr = true
+			default:
+				return c, fmt.Errorf("invalid constructor %q: unknown flag %q, expected %s or %s", value, flag, constructorFlagValidate, constructorFlagPtr)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c Constructor) String() string {
+	var flags []string
+
+	if c.Validate {
+		flags = append(flags, constructorFlagValidate)
+	}
+
+	if c.Ptr {
+		flags = append(flags, constructorFlagPtr)
+	}
+
+	if len(flags) > 0 {
+		return fmt.Sprintf("%s:%s:%s", c.Name, strings.Join(c.Params, ","), strings.Join(flags, ","))
+	}
+
+	if len(c.Params) == 0 {
+		return c.Name
+	}
+
+	return fmt.Sprintf("%s:%s", c.Name, strings.Join(c.Params, ","))
+}
+
+// validates reports whether any of the command's constructors returns the result of its Validate method.
+func (c *Command) validates() bool {
+	for _, constructor := range c.Constructors {
+		if constructor.Validate {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fields returns names of the constructor's parameters, expanding "*" to given fields of the command.
+func (c Constructor) fields(commandFields []string) ([]string, error) {
+	if len(c.Params) == 0 || c.Params[0] != allConstructorParams {
+		return c.Params, nil
+	}
+
+	leftOut := map[string]bool{}
+
+	for _, param := range c.Params[1:] {
+		leftOut[strings.TrimPrefix(param, "-")] = true
+	}
+
+	fields := make([]string, 0, len(commandFields))
+
+	for _, field := range commandFields {
+		if leftOut[field] {
+			delete(leftOut, field)
+		} else {
+			fields = append(fields, field)
+		}
+	}
+
+	if len(leftOut) > 0 {
+		return nil, fmt.Errorf("cannot leave out fields %s: they do not exist, are excluded or not included", strings.Join(sortedKeys(leftOut), ", "))
+	}
+
+	return fields, nil
+}
+
+func (c *Constructor) UnmarshalText(text []byte) (err error) {
+	*c, err = ParseConstructor(string(text))
+
+	return
+}
+
+func (c Constructor) UniqueValue() any {
+	return c.Name
+}
+
+// ParseEmbedded parses "Field:mode" embedded field's mode specification, the field given by its path, e.g. "Base.Audit".
+func ParseEmbedded(value string) (name string, mode EmbeddedMode, _ error) {
+	name, modeValue, ok := strings.Cut(value, ":")
+	if !ok || name == "" || modeValue == "" {
+		return "", "", fmt.Errorf("invalid embedded field mode %q, expected Field:mode", value)
+	}
+
+	return name, EmbeddedMode(modeValue), nil
+}
+
+// ParseRename parses "Field:Name" field's rename specification.
+func ParseRename(value string) (path string, name string, _ error) {
+	path, name, ok := strings.Cut(value, ":")
+	if !ok || path == "" || name == "" {
+		return "", "", fmt.Errorf("invalid field rename %q, expected Field:Name", value)
+	}
+
+	return path, name, nil
+}
+
+// ParseNested parses "Field[:CommandName]" nested command specification.
+func ParseNested(value string) (path string, name string, _ error) {
+	path, name, _ = strings.Cut(value, ":")
+	if path == "" {
+		return "", "", fmt.Errorf("invalid nested command %q, expected Field[:CommandName]", value)
+	}
+
+	return path, name, nil
+}
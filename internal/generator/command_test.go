@@ -0,0 +1,168 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConstructor(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected Constructor
+		wantErr  bool
+	}{
+		{
+			name:     "default constructor",
+			value:    "default",
+			expected: Constructor{Name: "default"},
+		},
+		{
+			name:     "fields",
+			value:    "WithFoo:Foo,Bar",
+			expected: Constructor{Name: "WithFoo", Params: []string{"Foo", "Bar"}},
+		},
+		{
+			name:     "all fields with left out ones and flags",
+			value:    "All:*,-Internal:validate,ptr",
+			expected: Constructor{Name: "All", Params: []string{"*", "-Internal"}, Validate: true, Ptr: true},
+		},
+		{
+			name:     "flags without fields",
+			value:    "Validated::validate",
+			expected: Constructor{Name: "Validated", Validate: true},
+		},
+		{
+			name:    "missing name",
+			value:   ":Foo",
+			wantErr: true,
+		},
+		{
+			name:    "name not usable in an identifier",
+			value:   "With-Foo:Foo",
+			wantErr: true,
+		},
+		{
+			name:    "* not first",
+			value:   "All:Foo,*",
+			wantErr: true,
+		},
+		{
+			name:    "left out field without *",
+			value:   "All:-Foo",
+			wantErr: true,
+		},
+		{
+			name:    "field already included by *",
+			value:   "All:*,Foo",
+			wantErr: true,
+		},
+		{
+			name:    "field not being an identifier",
+			value:   "WithFoo:Foo.Bar",
+			wantErr: true,
+		},
+		{
+			name:    "duplicated field",
+			value:   "WithFoo:Foo,Foo",
+			wantErr: true,
+		},
+		{
+			name:    "duplicated left out field",
+			value:   "All:*,-Foo,-Foo",
+			wantErr: true,
+		},
+		{
+			name:    "unknown flag",
+			value:   "All:*:validate,strict",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ParseConstructor(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseConstructor(%q) expected error, got %+v", tt.value, actual)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseConstructor(%q) unexpected error: %v", tt.value, err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("ParseConstructor(%q) = %+v, expected %+v", tt.value, actual, tt.expected)
+			}
+
+			// Invocation records constructors with String, so it has to parse back to the same constructor.
+			reparsed, err := ParseConstructor(actual.String())
+			if err != nil {
+				t.Fatalf("ParseConstructor(%q) of String() unexpected error: %v", actual.String(), err)
+			}
+
+			if !reflect.DeepEqual(reparsed, actual) {
+				t.Errorf("ParseConstructor(%q) of String() = %+v, expected %+v", actual.String(), reparsed, actual)
+			}
+		})
+	}
+}
+
+func TestConstructorFields(t *testing.T) {
+	commandFields := []string{"Foo", "Bar", "Baz"}
+
+	tests := []struct {
+		name     string
+		params   []string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name: "no fields",
+		},
+		{
+			name:     "given fields",
+			params:   []string{"Baz", "Foo"},
+			expected: []string{"Baz", "Foo"},
+		},
+		{
+			name:     "all fields",
+			params:   []string{"*"},
+			expected: []string{"Foo", "Bar", "Baz"},
+		},
+		{
+			name:     "all fields but left out ones",
+			params:   []string{"*", "-Bar"},
+			expected: []string{"Foo", "Baz"},
+		},
+		{
+			name:    "left out field does not exist",
+			params:  []string{"*", "-Bar", "-Qux"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := Constructor{Name: "Test", Params: tt.params}.fields(commandFields)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("fields() expected error, got %v", actual)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("fields() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("fields() = %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
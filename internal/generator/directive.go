@@ -0,0 +1,208 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+)
+
+const directivePrefix = "//cmder:command"
+
+// Discover loads packages matching given patterns and builds commands from
+// //cmder:command directives found in the doc comments of their structs:
+//
+//	//cmder:command CreateStructCmd constructor=default sorted
+//	type Struct struct{}
+//
+// Commands are generated in the package of the annotated struct. Problems of all
+// directives are joined together, valid commands are returned regardless.
+func (g *Generator) Discover(patterns ...string) ([]Command, error) {
+	pkgs, err := g.loader.LoadAll(patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load packages: %w", err)
+	}
+
+	var (
+		commands []Command
+		errs     []error
+	)
+
+	for _, pkg := range pkgs {
+		if !tolerableErrors(pkg) {
+			errs = append(errs, packageErrors(pkg))
+
+			continue
+		}
+
+		dir := packageDir(pkg)
+
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+
+					doc := typeSpec.Doc
+					if doc == nil && !genDecl.Lparen.IsValid() {
+						doc = genDecl.Doc
+					}
+
+					if doc == nil {
+						continue
+					}
+
+					for _, comment := range doc.List {
+						if !isDirective(comment.Text) {
+							continue
+						}
+
+						position := pkg.Fset.Position(comment.Pos())
+
+						if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+							errs = append(errs, diagnostic.Errorf(position, "%s is not a struct", typeSpec.Name.Name))
+
+							continue
+						}
+
+						command, err := parseDirective(comment.Text)
+						if err != nil {
+							errs = append(errs, diagnostic.Errorf(position, "%w", err))
+
+							continue
+						}
+
+						command.Struct = typeSpec.Name.Name
+						command.Package = dir
+						command.Position = position
+
+						commands = append(commands, command)
+					}
+				}
+			}
+		}
+	}
+
+	return commands, errors.Join(errs...)
+}
+
+func isDirective(text string) bool {
+	return text == directivePrefix || strings.HasPrefix(text, directivePrefix+" ")
+}
+
+// parseDirective parses "//cmder:command CommandName [option...]" comment, where option
+// is one of: mutable, sorted, include-unexported, initialisms (optionally followed by =true or =false),
+// out=file.go, include=Foo,Bar, exclude=Foo,Bar, embedded=Field:mode, nested=Field[:CommandName],
+// rename=Field:Name, getter-prefix=Get, setter-prefix=With, haser-prefix=IsSet,
+// collisions=suffix[:Affix] or constructor=name[:fields[:flags]].
+func parseDirective(text string) (command Command, _ error) {
+	args := strings.Fields(strings.TrimPrefix(text, directivePrefix))
+	if len(args) == 0 {
+		return command, errors.New("missing command name in directive")
+	}
+
+	command.Name = args[0]
+
+	for _, arg := range args[1:] {
+		key, value, hasValue := strings.Cut(arg, "=")
+
+		switch key {
+		case "mutable", "sorted", "include-unexported", "initialisms":
+			enabled := true
+
+			if hasValue {
+				var err error
+
+				if enabled, err = strconv.ParseBool(value); err != nil {
+					return command, fmt.Errorf("invalid value of %s option: %q", key, value)
+				}
+			}
+
+			switch key {
+			case "mutable":
+				command.Mutable = enabled
+			case "sorted":
+				command.Sorted = enabled
+			case "initialisms":
+				command.Initialisms = enabled
+			default:
+				command.IncludeUnexported = enabled
+			}
+
+		case "out", "include", "exclude", "constructor", "embedded", "nested", "rename", "getter-prefix", "setter-prefix", "haser-prefix", "collisions":
+			if value == "" {
+				return command, fmt.Errorf("missing value of %s option", key)
+			}
+
+			switch key {
+			case "out":
+				command.Out = value
+			case "getter-prefix":
+				command.GetterPrefix = value
+			case "setter-prefix":
+				command.SetterPrefix = value
+			case "haser-prefix":
+				command.HaserPrefix = value
+			case "collisions":
+				command.Collisions = value
+			case "rename":
+				path, name, err := ParseRename(value)
+				if err != nil {
+					return command, err
+				}
+
+				if command.Renames == nil {
+					command.Renames = map[string]string{}
+				}
+
+				command.Renames[path] = name
+			case "include":
+				command.Include = append(command.Include, value)
+			case "exclude":
+				command.Exclude = append(command.Exclude, value)
+			case "nested":
+				path, name, err := ParseNested(value)
+				if err != nil {
+					return command, err
+				}
+
+				if command.Nested == nil {
+					command.Nested = map[string]string{}
+				}
+
+				command.Nested[path] = name
+			case "embedded":
+				name, mode, err := ParseEmbedded(value)
+				if err != nil {
+					return command, err
+				}
+
+				if command.Embedded == nil {
+					command.Embedded = map[string]EmbeddedMode{}
+				}
+
+				command.Embedded[name] = mode
+			default:
+				constructor, err := ParseConstructor(value)
+				if err != nil {
+					return command, err
+				}
+
+				command.Constructors = append(command.Constructors, constructor)
+			}
+
+		default:
+			return command, fmt.Errorf("unknown directive option %q", key)
+		}
+	}
+
+	return command, nil
+}
@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+)
+
+// EmbeddedMode tells how to treat an embedded field.
+type EmbeddedMode string
+
+const (
+	// EmbeddedKeep keeps the embedded field as a single command field. This is the default.
+	EmbeddedKeep EmbeddedMode = "keep"
+	// EmbeddedFlatten replaces the embedded field with its promoted fields.
+	EmbeddedFlatten EmbeddedMode = "flatten"
+	// EmbeddedSkip ignores the embedded field.
+	EmbeddedSkip EmbeddedMode = "skip"
+)
+
+// embeddedTagKey is the struct tag key to set embedded field's mode with, e.g. `cmder:"flatten"`.
+const embeddedTagKey = "cmder"
+
+// structField is a source struct field, possibly promoted from a flattened embedded field.
+type structField struct {
+	field  *types.Var
+	tag    string
+	syntax *ast.Field
+	// path is the selector path from the source struct, e.g. ["Base", "Name"].
+	path []string
+	// accessor is the field's name in the command's identifiers, set when the field is selected.
+	accessor string
+}
+
+func (f *structField) depth() int {
+	return len(f.path)
+}
+
+// collectStructFields lists fields of the struct, flattening embedded fields according to the command's options
+// and their tags. Options are keyed by embedded fields' paths, prefixed with keyPrefix for nested commands.
+// Following Go's promotion rules, a promoted field is shadowed by a field of the same name
+// at a shallower depth, while fields of the same name at the same depth conflict.
+func (p *plan) collectStructFields(structType *types.Struct, source *structSource, keyPrefix string) ([]*structField, error) {
+	pkg := p.sourcePkg
+
+	var fields []*structField
+
+	var walk func(structType *types.Struct, source *structSource, path []string) error
+	walk = func(structType *types.Struct, source *structSource, path []string) error {
+		for i := 0; i < structType.NumFields(); i++ {
+			field := &structField{
+				field:  structType.Field(i),
+				tag:    structType.Tag(i),
+				syntax: source.Field(structType.Field(i).Pos()),
+				path:   append(path[:len(path):len(path)], structType.Field(i).Name()),
+			}
+
+			key := keyPrefix + strings.Join(field.path, ".")
+
+			if _, ok := p.command.Embedded[key]; ok {
+				p.usedEmbedded[key] = true
+
+				if !field.field.Embedded() {
+					return diagnostic.Errorf(pkg.Fset.Position(field.field.Pos()), "field %s is not embedded and cannot have embedded mode", key)
+				}
+			}
+
+			if !field.field.Embedded() {
+				fields = append(fields, field)
+
+				continue
+			}
+
+			mode, err := p.command.embeddedMode(key, field)
+			if err != nil {
+				return diagnostic.Errorf(pkg.Fset.Position(field.field.Pos()), "%w", err)
+			}
+
+			switch mode {
+			case EmbeddedSkip:
+				continue
+
+			case EmbeddedKeep:
+				fields = append(fields, field)
+
+				continue
+			}
+
+			embeddedType := field.field.Type()
+			if pointer, ok := embeddedType.(*types.Pointer); ok {
+				embeddedType = pointer.Elem()
+			}
+
+			embeddedStruct, ok := embeddedType.Underlying().(*types.Struct)
+			if !ok {
+				return diagnostic.Errorf(pkg.Fset.Position(field.field.Pos()), "embedded field %s is not a struct and cannot be flattened", strings.Join(field.path, "."))
+			}
+
+			var embeddedSource *structSource
+
+			if named, ok := embeddedType.(*types.Named); ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkg.PkgPath {
+				embeddedSource = findStructSource(pkg, named.Origin().Obj())
+			}
+
+			if err := walk(embeddedStruct, embeddedSource, field.path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := walk(structType, source, nil); err != nil {
+		return nil, err
+	}
+
+	minDepths := map[string]int{}
+
+	for _, field := range fields {
+		if depth, ok := minDepths[field.field.Name()]; !ok || field.depth() < depth {
+			minDepths[field.field.Name()] = field.depth()
+		}
+	}
+
+	visible := make([]*structField, 0, len(fields))
+	visibleByName := map[string]*structField{}
+
+	for _, field := range fields {
+		if field.depth() != minDepths[field.field.Name()] {
+			continue
+		}
+
+		if other, ok := visibleByName[field.field.Name()]; ok {
+			return nil, diagnostic.Errorf(
+				pkg.Fset.Position(field.field.Pos()),
+				"field %s conflicts with %s after flattening embedded fields",
+				strings.Join(field.path, "."),
+				strings.Join(other.path, "."),
+			)
+		}
+
+		visibleByName[field.field.Name()] = field
+		visible = append(visible, field)
+	}
+
+	return visible, nil
+}
+
+// embeddedMode returns the mode of the embedded field of given key: the one set in the command's options
+// takes precedence over the one from the field's tag.
+func (c *Command) embeddedMode(key string, field *structField) (EmbeddedMode, error) {
+	mode, ok := c.Embedded[key]
+	if !ok {
+		mode = EmbeddedMode(reflect.StructTag(field.tag).Get(embeddedTagKey))
+	}
+
+	switch mode {
+	case "":
+		return EmbeddedKeep, nil
+
+	case EmbeddedKeep, EmbeddedFlatten, EmbeddedSkip:
+		return mode, nil
+
+	default:
+		return "", fmt.Errorf("invalid mode %q of embedded field %s, expected one of: keep, flatten, skip", mode, strings.Join(field.path, "."))
+	}
+}
+
+// deprecation returns the first paragraph of given comments' texts starting with "Deprecated:", or an empty string.
+func deprecation(texts ...string) string {
+	for _, text := range texts {
+		for _, paragraph := range strings.Split(text, "\n\n") {
+			if strings.HasPrefix(paragraph, "Deprecated:") {
+				return strings.TrimSpace(paragraph)
+			}
+		}
+	}
+
+	return ""
+}
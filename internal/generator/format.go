@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"strings"
+
+	"golang.org/x/tools/imports"
+)
+
+// maxReportedSyntaxErrors limits syntax errors reported for generated source that does not parse.
+const maxReportedSyntaxErrors = 5
+
+// formatSource formats generated source like gofmt and groups its imports like goimports.
+// When the source does not parse, returned error points at the offending lines of the unformatted source.
+func formatSource(filename string, src []byte) ([]byte, error) {
+	formatted, err := imports.Process(filename, src, &imports.Options{
+		FormatOnly: true,
+		Comments:   true,
+		TabIndent:  true,
+		TabWidth:   8,
+	})
+	if err == nil {
+		return formatted, nil
+	}
+
+	var errorList scanner.ErrorList
+	if !errors.As(err, &errorList) {
+		return nil, fmt.Errorf("failed to format generated source: %w", err)
+	}
+
+	lines := bytes.Split(src, []byte("\n"))
+
+	var message strings.Builder
+
+	message.WriteString("generated source does not parse, check the templates:")
+
+	for i, syntaxErr := range errorList {
+		if i == maxReportedSyntaxErrors {
+			fmt.Fprintf(&message, "\n(and %d more errors)", len(errorList)-i)
+
+			break
+		}
+
+		fmt.Fprintf(&message, "\n%s (unformatted source)", syntaxErr)
+
+		if line := syntaxErr.Pos.Line; line > 0 && line <= len(lines) {
+			fmt.Fprintf(&message, "\n\t%d | %s", line, lines[line-1])
+		}
+	}
+
+	return nil, errors.New(message.String())
+}
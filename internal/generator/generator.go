@@ -0,0 +1,652 @@
+package generator
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+	"github.com/donatorsky/go-cmder/internal/template"
+	internalTypes "github.com/donatorsky/go-cmder/internal/types"
+	"github.com/donatorsky/go-cmder/internal/utils"
+)
+
+var filenamePattern = regexp.MustCompile(`(ID|JSON|URL|[[:upper:]])`)
+
+// StdoutOut is the output file name making the command be written to the standard output.
+const StdoutOut = "-"
+
+type generatorOptions struct {
+	ctx          context.Context
+	templatesDir string
+}
+
+type option func(options *generatorOptions)
+
+// WithContext sets the context used for loading packages and cancelling generation.
+func WithContext(ctx context.Context) option {
+	return func(options *generatorOptions) {
+		options.ctx = ctx
+	}
+}
+
+// WithTemplatesDir makes the generator use templates from given directory, see template.TemplateWithDir.
+func WithTemplatesDir(dir string) option {
+	return func(options *generatorOptions) {
+		options.templatesDir = dir
+	}
+}
+
+func New(dir string, options ...option) (*Generator, error) {
+	generatorOptions := &generatorOptions{
+		ctx: context.Background(),
+	}
+
+	for _, option := range options {
+		option(generatorOptions)
+	}
+
+	tpl, err := template.NewTemplate(template.TemplateWithDir(generatorOptions.templatesDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse command template: %w", err)
+	}
+
+	return &Generator{
+		dir:     dir,
+		loader:  newLoader(generatorOptions.ctx, dir),
+		tpl:     tpl,
+		options: generatorOptions,
+	}, nil
+}
+
+type Generator struct {
+	dir     string
+	loader  *loader
+	tpl     *template.Template
+	options *generatorOptions
+}
+
+// GenerateAll generates all given commands, loading their packages at once, and returns their
+// sources by output paths. Commands written to StdoutOut are concatenated in the given order.
+// Sources written to files of the same package are type-checked together, so hand-written code
+// of the package may refer to several commands generated for the first time.
+// It does not stop on the first failure: errors of all commands are joined together and sources
+// of the commands generated successfully are returned regardless.
+func (g *Generator) GenerateAll(commands []Command) (map[string][]byte, error) {
+	var (
+		errs     []error
+		valid    []Command
+		patterns []string
+	)
+
+	for _, command := range commands {
+		if err := command.normalize(); err != nil {
+			errs = append(errs, command.diagnostics(err)...)
+
+			continue
+		}
+
+		valid = append(valid, command)
+		patterns = append(patterns, command.Package)
+
+		if command.SourcePkg != "" {
+			patterns = append(patterns, command.SourcePkg)
+		}
+	}
+
+	if err := g.loader.Load(patterns...); err != nil {
+		return nil, errors.Join(append(errs, fmt.Errorf("could not load packages: %w", err))...)
+	}
+
+	var (
+		generated []*generatedCommand
+		// batches holds sources written to files by target packages and output paths.
+		batches = map[string]map[string][]byte{}
+	)
+
+	for _, command := range valid {
+		if err := g.options.ctx.Err(); err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+
+		c, err := g.generate(command)
+		if err != nil {
+			errs = append(errs, command.diagnostics(err)...)
+
+			continue
+		}
+
+		if command.Out != StdoutOut {
+			batch, ok := batches[c.plan.targetPkg.PkgPath]
+			if !ok {
+				batch = map[string][]byte{}
+				batches[c.plan.targetPkg.PkgPath] = batch
+			}
+
+			if _, ok := batch[c.path]; ok {
+				errs = append(errs, command.diagnostics(fmt.Errorf("output file %s is already used by another command", c.path))...)
+
+				continue
+			}
+
+			batch[c.path] = c.source
+		}
+
+		generated = append(generated, c)
+	}
+
+	files := make(map[string][]byte, len(generated))
+
+	for _, c := range generated {
+		if err := g.options.ctx.Err(); err != nil {
+			return files, errors.Join(append(errs, err)...)
+		}
+
+		outputPath, content, err := c.verify(batches[c.plan.targetPkg.PkgPath])
+		if err != nil {
+			errs = append(errs, c.plan.command.diagnostics(err)...)
+		}
+
+		if content != nil {
+			files[outputPath] = append(files[outputPath], content...)
+		}
+	}
+
+	return files, errors.Join(errs...)
+}
+
+// Generate generates given command and returns its output path and source. Returned error holds
+// diagnostics of the problems found; when all of them are warnings, the source is returned too.
+func (g *Generator) Generate(command Command) (outputPath string, _ []byte, _ error) {
+	generated, err := g.generate(command)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return generated.verify(nil)
+}
+
+// generatedCommand is the source of a command, generated but not verified yet.
+type generatedCommand struct {
+	plan *plan
+	// path is the output file the source is verified as, also when the command is written to stdout.
+	path   string
+	source []byte
+}
+
+// verify verifies the command's source together with given sources of other commands generated
+// at once, by their output paths, and returns its output path and source, with warnings.
+func (c *generatedCommand) verify(batch map[string][]byte) (outputPath string, _ []byte, _ error) {
+	if err := verifySource(c.plan, c.path, c.source, batch); err != nil {
+		return "", nil, err
+	}
+
+	outputPath = c.path
+	if c.plan.command.Out == StdoutOut {
+		outputPath = StdoutOut
+	}
+
+	return outputPath, c.source, errors.Join(c.plan.warnings()...)
+}
+
+// generate generates given command's source, without verifying it.
+func (g *Generator) generate(command Command) (*generatedCommand, error) {
+	if err := command.normalize(); err != nil {
+		return nil, err
+	}
+
+	targetPkg, err := g.loader.TargetPackage(command.Package)
+	if err != nil {
+		return nil, err
+	}
+
+	sourcePkg := targetPkg
+
+	if command.SourcePkg != "" {
+		sourcePkg, err = g.loader.Package(command.SourcePkg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	typesRegistry := internalTypes.NewRegistry(targetPkg, sourcePkg)
+
+	structName, typeArgs, err := splitTypeArgs(command.Struct)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := sourcePkg.Types.Scope().Lookup(structName)
+	if obj == nil {
+		return nil, fmt.Errorf("struct %s not found in package %s", structName, sourcePkg.PkgPath)
+	}
+
+	structPosition := sourcePkg.Fset.Position(obj.Pos())
+
+	structTypeOrInstance, typeParams, err := resolveStructType(sourcePkg, obj, typeArgs)
+	if err != nil {
+		return nil, diagnostic.Errorf(structPosition, "%w", err)
+	}
+
+	structType, ok := structTypeOrInstance.Underlying().(*types.Struct)
+	if !ok {
+		return nil, diagnostic.Errorf(structPosition, "%s (%s) is not a struct", structName, obj.Type())
+	}
+
+	source := findStructSource(sourcePkg, obj)
+
+	typesRegistry.Reserve(command.Name, mirrorName(command.Name), "cmd", "v")
+
+	for i := 0; i < typeParams.Len(); i++ {
+		typesRegistry.Reserve(typeParams.At(i).Obj().Name())
+	}
+
+	for _, constructor := range command.Constructors {
+		typesRegistry.Reserve("New" + template.Title(constructorName(command.Name, constructor)))
+	}
+
+	p := &plan{
+		command:        &command,
+		targetPkg:      targetPkg,
+		sourcePkg:      sourcePkg,
+		registry:       typesRegistry,
+		tpl:            g.tpl,
+		structPosition: structPosition,
+		structType:     structType,
+		usedNested:     map[string]bool{},
+		usedEmbedded:   map[string]bool{},
+		usedRenames:    map[string]bool{},
+	}
+
+	if p.include, err = parseSelectors(command.Include, sourcePkg.Types); err != nil {
+		return nil, err
+	}
+
+	if p.exclude, err = parseSelectors(command.Exclude, sourcePkg.Types); err != nil {
+		return nil, err
+	}
+
+	root, err := p.planCommand(command.Name, structType, source, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	root.source = &template.SourceData{
+		Name:            structName,
+		PackageName:     sourcePkg.Name,
+		PackagePath:     sourcePkg.PkgPath,
+		Doc:             source.Doc(),
+		BuildConstraint: source.BuildConstraint(),
+		Position:        structPosition,
+	}
+
+	if unused := unusedKeys(command.Nested, p.usedNested); len(unused) > 0 {
+		return nil, fmt.Errorf("cannot generate nested commands: fields %s do not exist, are excluded or not included", strings.Join(unused, ", "))
+	}
+
+	if unused := unusedKeys(command.Embedded, p.usedEmbedded); len(unused) > 0 {
+		return nil, fmt.Errorf("cannot set modes of embedded fields %s: they do not exist, or are not in flattened fields or nested commands", strings.Join(unused, ", "))
+	}
+
+	p.typeParams, p.typeArgs, err = typesRegistry.ResolveTypeParams(typeParams)
+	if err != nil {
+		return nil, diagnostic.Errorf(structPosition, "cannot resolve type parameters of %s: %w", structName, err)
+	}
+
+	var nested []string
+
+	commandData, err := g.renderCommand(p, root, command.Constructors, &nested)
+	if err != nil {
+		return nil, err
+	}
+
+	commandData.Nested = nested
+	invoked := command
+	invoked.SourcePkg = g.relativeSourcePkg(&command)
+
+	commandData.Invocation = invoked.Invocation(g.relativeTemplatesDir(&command))
+	commandData.SourceHash = sourceHash(structTypeOrInstance)
+
+	if commandData.DriftGuard, err = g.renderDriftGuard(p, command.Name, structTypeOrInstance, structPosition); err != nil {
+		return nil, err
+	}
+
+	commandData.Imports = typesRegistry.Imports()
+
+	var b bytes.Buffer
+
+	if err := g.tpl.ExecuteCommandTemplate(&b, commandData); err != nil {
+		return nil, fmt.Errorf("failed to generate command: %w", err)
+	}
+
+	outputPath := g.outputPath(&command)
+
+	formatted, err := formatSource(outputPath, b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return &generatedCommand{
+		plan:   p,
+		path:   outputPath,
+		source: formatted,
+	}, nil
+}
+
+// renderCommand renders the command's constructors and methods. Nested commands are rendered
+// first and appended to nested list.
+func (g *Generator) renderCommand(p *plan, node *commandNode, constructors []Constructor, nested *[]string) (*template.CommandData, error) {
+	var err error
+
+	fields := utils.NewUniqueSlice[*template.FieldData](
+		utils.UniqueSliceWithCapacity(uint(len(node.fields))),
+	)
+
+	var errs []error
+
+	for _, structField := range node.fields {
+		field := structField.field
+
+		commandDataField := &template.FieldData{
+			CommandName:  node.name,
+			TypeParams:   p.typeParams,
+			TypeArgs:     p.typeArgs,
+			Mutable:      p.command.Mutable,
+			Name:         field.Name(),
+			Path:         p.selector(node.path, structField),
+			PointerSteps: p.pointerSteps(append(node.path[:len(node.path):len(node.path)], structField.path...)),
+			AccessorName: structField.accessor,
+			Getter:       p.command.getterPrefix() + structField.accessor,
+			Setter:       p.command.setterPrefix() + structField.accessor,
+			Haser:        p.command.haserPrefix() + structField.accessor,
+			Kind:         internalTypes.KindOf(field.Type()),
+			Tag:          reflect.StructTag(structField.tag),
+			Tags:         parseTags(structField.tag),
+			Position:     p.sourcePkg.Fset.Position(field.Pos()),
+			Embedded:     field.Embedded(),
+			Exported:     field.Exported(),
+		}
+
+		if structField.syntax != nil {
+			commandDataField.Doc = structField.syntax.Doc.Text()
+			commandDataField.Comment = structField.syntax.Comment.Text()
+			commandDataField.Deprecated = deprecation(commandDataField.Doc, commandDataField.Comment)
+		}
+
+		if nestedNode, ok := node.nested[structField]; ok {
+			nestedData, err := g.renderCommand(p, nestedNode, []Constructor{{Name: "default"}}, nested)
+			if err != nil {
+				return nil, err
+			}
+
+			var b bytes.Buffer
+
+			if err := g.tpl.ExecuteNestedTemplate(&b, nestedData); err != nil {
+				return nil, fmt.Errorf("failed to generate nested command %s: %w", nestedNode.name, err)
+			}
+
+			*nested = append(*nested, b.String())
+
+			if p.command.Mutable {
+				commandDataField.Pointer = "*"
+			}
+
+			commandDataField.Type = nestedNode.name + p.typeArgs
+			commandDataField.NestedCommand = nestedNode.name
+		} else {
+			commandDataField.Pointer, commandDataField.Type, err = p.registry.Resolve(field.Type())
+			if err != nil {
+				errs = append(errs, diagnostic.Errorf(commandDataField.Position, "cannot resolve type of field %s: %w", commandDataField.Path, err))
+
+				continue
+			}
+		}
+
+		if fields.Has(commandDataField) {
+			errs = append(errs, diagnostic.Errorf(commandDataField.Position, "fields' names conflict with %q", commandDataField.Path))
+
+			continue
+		}
+
+		_, _ = fields.Append(commandDataField)
+
+		p.fields = append(p.fields, commandDataField)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if p.command.Sorted {
+		fields.Sort(func(i, j *template.FieldData) int {
+			return cmp.Compare(i.Name, j.Name)
+		})
+	}
+
+	var b bytes.Buffer
+
+	constructorNames := utils.NewUniqueSlice[Constructor](
+		utils.UniqueSliceWithOnDuplicateKeyError(func(key, item any) error {
+			return fmt.Errorf("duplicated constructor name %q", key)
+		}),
+	)
+
+	fieldsByName := make(map[string]*template.FieldData, fields.Len())
+	fieldNames := make([]string, 0, fields.Len())
+
+	for _, field := range fields.Items() {
+		fieldsByName[field.Name] = field
+		fieldNames = append(fieldNames, field.Name)
+	}
+
+	var renderedConstructors []string
+	for _, constructor := range constructors {
+		if _, err := constructorNames.Append(constructor); err != nil {
+			return nil, err
+		}
+
+		constructorData := template.ConstructorData{
+			CommandName: node.name,
+			TypeParams:  p.typeParams,
+			TypeArgs:    p.typeArgs,
+			Mutable:     p.command.Mutable,
+			Name:        constructorName(node.name, constructor),
+			Validate:    constructor.Validate,
+			Ptr:         constructor.Ptr,
+		}
+
+		params, err := constructor.fields(fieldNames)
+		if err != nil {
+			return nil, fmt.Errorf("cannot build %s constructor: %w", constructor.Name, err)
+		}
+
+		for _, param := range params {
+			fieldData, ok := fieldsByName[param]
+			if !ok {
+				return nil, fmt.Errorf("cannot build %s constructor: field %s does not exist, is excluded or not included", constructor.Name, param)
+			}
+
+			constructorData.Fields = append(constructorData.Fields, fieldData)
+		}
+
+		if err := g.tpl.ExecuteConstructorTemplate(&b, &constructorData); err != nil {
+			return nil, fmt.Errorf("failed to generate command constructor: %w", err)
+		}
+
+		renderedConstructors = append(renderedConstructors, b.String())
+		b.Reset()
+	}
+
+	var methods []string
+
+	for _, field := range fields.Items() {
+		if err := g.tpl.ExecuteGetterTemplate(&b, field); err != nil {
+			return nil, fmt.Errorf("failed to generate command getter: %w", err)
+		}
+
+		methods = append(methods, b.String())
+		b.Reset()
+
+		if err := g.tpl.ExecuteSetterTemplate(&b, field); err != nil {
+			return nil, fmt.Errorf("failed to generate command setter: %w", err)
+		}
+
+		methods = append(methods, b.String())
+		b.Reset()
+
+		if err := g.tpl.ExecuteHaserTemplate(&b, field); err != nil {
+			return nil, fmt.Errorf("failed to generate command haser: %w", err)
+		}
+
+		methods = append(methods, b.String())
+		b.Reset()
+
+		fieldMethods, err := g.tpl.ExecuteFieldTemplates(field)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate field %s methods: %w", field.Name, err)
+		}
+
+		methods = append(methods, fieldMethods...)
+	}
+
+	commandData := &template.CommandData{
+		PackageName:  p.targetPkg.Name,
+		Imports:      p.registry.Imports(),
+		CommandName:  node.name,
+		TypeParams:   p.typeParams,
+		TypeArgs:     p.typeArgs,
+		Fields:       fields.Items(),
+		Constructors: renderedConstructors,
+		Methods:      methods,
+		Source:       node.source,
+	}
+
+	commandMethods, err := g.tpl.ExecuteExtraCommandTemplates(commandData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate command methods: %w", err)
+	}
+
+	commandData.Methods = append(commandData.Methods, commandMethods...)
+
+	return commandData, nil
+}
+
+func constructorName(commandName string, constructor Constructor) string {
+	if strings.ToLower(constructor.Name) == "default" {
+		return commandName
+	}
+
+	return fmt.Sprintf("%s%s", commandName, constructor.Name)
+}
+
+func (g *Generator) outputPath(command *Command) string {
+	// Command written to stdout is verified as if it was written to its default output file.
+	if command.Out == StdoutOut {
+		return filepath.Join(g.loader.dir(command.Package), defaultOut(command.Name))
+	}
+
+	if filepath.IsAbs(command.Out) {
+		return command.Out
+	}
+
+	return filepath.Join(g.loader.dir(command.Package), command.Out)
+}
+
+// relativeTemplatesDir returns the templates directory relative to the command's package directory,
+// so the recorded invocation works when run there, e.g. by go generate.
+func (g *Generator) relativeTemplatesDir(command *Command) string {
+	if g.options.templatesDir == "" {
+		return ""
+	}
+
+	templatesDir, err := filepath.Abs(g.options.templatesDir)
+	if err != nil {
+		return g.options.templatesDir
+	}
+
+	relative, err := filepath.Rel(g.loader.dir(command.Package), templatesDir)
+	if err != nil {
+		return templatesDir
+	}
+
+	return filepath.ToSlash(relative)
+}
+
+// relativeSourcePkg returns the source package given as a filesystem path relative to the command's package
+// directory, e.g. ../domain for ./domain source package of ./app package, so the recorded invocation works
+// when run there. Import paths are returned unchanged.
+func (g *Generator) relativeSourcePkg(command *Command) string {
+	if !build.IsLocalImport(command.SourcePkg) && !filepath.IsAbs(command.SourcePkg) {
+		return command.SourcePkg
+	}
+
+	sourceDir := g.loader.dir(command.SourcePkg)
+
+	relative, err := filepath.Rel(g.loader.dir(command.Package), sourceDir)
+	if err != nil {
+		return sourceDir
+	}
+
+	relative = filepath.ToSlash(relative)
+	if !build.IsLocalImport(relative) {
+		relative = "./" + relative
+	}
+
+	return relative
+}
+
+// normalize splits package path from the struct name and fills in the defaults.
+func (c *Command) normalize() error {
+	if c.Struct == "" {
+		return errors.New("missing struct name")
+	}
+
+	if c.Name == "" {
+		return errors.New("missing command name")
+	}
+
+	// Package path is separated from the struct name by the last dot before type arguments, if any.
+	structName, _, _ := strings.Cut(c.Struct, "[")
+
+	if i := strings.LastIndex(structName, "."); i >= 0 {
+		if c.SourcePkg != "" {
+			return fmt.Errorf("source package given both as source package (%s) and in struct name (%s)", c.SourcePkg, c.Struct)
+		}
+
+		if pkg, name := c.Struct[:i], c.Struct[i+1:]; pkg == "" || name == "" || strings.HasPrefix(name, "[") {
+			return fmt.Errorf("invalid struct %q, expected [path/to/pkg.]struct", c.Struct)
+		}
+
+		c.SourcePkg, c.Struct = c.Struct[:i], c.Struct[i+1:]
+	}
+
+	if c.Package == "" {
+		c.Package = "."
+	}
+
+	if c.Out == "" {
+		c.Out = defaultOut(c.Name)
+	}
+
+	if err := c.validateNaming(); err != nil {
+		return err
+	}
+
+	return c.validateCollisions()
+}
+
+// defaultOut returns the default output file name of given command, e.g. create_user_cmd.go for CreateUserCmd.
+func defaultOut(commandName string) string {
+	return fmt.Sprintf(
+		"%s.go",
+		strings.ToLower(strings.Trim(filenamePattern.ReplaceAllString(commandName, "_$1"), "_")),
+	)
+}
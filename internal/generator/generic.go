@@ -0,0 +1,118 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// splitTypeArgs splits struct name like "Page[foo.User, int]" into "Page" and its type arguments.
+func splitTypeArgs(structName string) (string, []string, error) {
+	i := strings.Index(structName, "[")
+	if i < 0 {
+		return structName, nil, nil
+	}
+
+	if !strings.HasSuffix(structName, "]") {
+		return "", nil, fmt.Errorf("invalid struct name %q: unterminated type arguments", structName)
+	}
+
+	var (
+		typeArgs []string
+		depth    int
+		start    = i + 1
+		list     = structName[:len(structName)-1]
+	)
+
+	for j := start; j < len(list); j++ {
+		switch list[j] {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				typeArgs = append(typeArgs, strings.TrimSpace(list[start:j]))
+				start = j + 1
+			}
+		}
+	}
+
+	typeArgs = append(typeArgs, strings.TrimSpace(list[start:]))
+
+	for _, typeArg := range typeArgs {
+		if typeArg == "" {
+			return "", nil, fmt.Errorf("invalid struct name %q: empty type argument", structName)
+		}
+	}
+
+	return structName[:i], typeArgs, nil
+}
+
+// resolveStructType returns the type to generate a command from. Without type arguments or when
+// they repeat the struct's type parameters' names, the generic type is returned together with
+// its type parameters. Otherwise, type arguments are evaluated in the scope of the struct's file
+// and the instantiated type is returned.
+func resolveStructType(pkg *packages.Package, obj types.Object, typeArgs []string) (types.Type, *types.TypeParamList, error) {
+	named, _ := obj.Type().(*types.Named)
+
+	var typeParams *types.TypeParamList
+	if named != nil {
+		typeParams = named.TypeParams()
+	}
+
+	if len(typeArgs) == 0 || isTypeParamsList(typeParams, typeArgs) {
+		return obj.Type(), typeParams, nil
+	}
+
+	if typeParams.Len() == 0 {
+		return nil, nil, fmt.Errorf("%s is not generic, but type arguments were given", obj.Name())
+	}
+
+	instanceTypeArgs := make([]types.Type, 0, len(typeArgs))
+
+	for _, typeArg := range typeArgs {
+		t, err := evalType(pkg, obj, typeArg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		instanceTypeArgs = append(instanceTypeArgs, t)
+	}
+
+	instance, err := types.Instantiate(nil, named.Origin(), instanceTypeArgs, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot instantiate %s: %w", obj.Name(), err)
+	}
+
+	return instance, nil, nil
+}
+
+func isTypeParamsList(typeParams *types.TypeParamList, names []string) bool {
+	if typeParams.Len() != len(names) {
+		return false
+	}
+
+	for i, name := range names {
+		if typeParams.At(i).Obj().Name() != name {
+			return false
+		}
+	}
+
+	return true
+}
+
+func evalType(pkg *packages.Package, obj types.Object, expr string) (types.Type, error) {
+	tv, err := types.Eval(pkg.Fset, pkg.Types, obj.Pos(), expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type argument %q: %w", expr, err)
+	}
+
+	if !tv.IsType() {
+		return nil, fmt.Errorf("invalid type argument %q: not a type", expr)
+	}
+
+	return tv.Type, nil
+}
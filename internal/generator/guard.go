@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+	"github.com/donatorsky/go-cmder/internal/template"
+)
+
+// mirrorName returns the name of the unexported mirror of the command's source struct, e.g. createUserCmdSource.
+func mirrorName(commandName string) string {
+	return strings.ToLower(commandName[:1]) + commandName[1:] + "Source"
+}
+
+// renderDriftGuard renders declarations breaking the build when fields of the source struct change:
+// an unexported mirror of the struct and a conversion of the mirror to the struct, which only compiles
+// while both have identical fields. When the struct cannot be mirrored in the target package, e.g. it comes
+// from another package and has unexported fields, an empty string is returned and the plan records a warning
+// at the struct's position.
+func (g *Generator) renderDriftGuard(p *plan, commandName string, structType types.Type, position token.Position) (string, error) {
+	leaveOut := func(reason error) (string, error) {
+		p.driftGuardWarning = diagnostic.Warningf(position, "drift guard left out: %v", reason)
+
+		return "", nil
+	}
+
+	structRef := structType
+
+	if named, ok := structType.(*types.Named); ok && named.TypeParams().Len() > 0 && named.TypeArgs().Len() == 0 {
+		typeArgs := make([]types.Type, 0, named.TypeParams().Len())
+		for i := 0; i < named.TypeParams().Len(); i++ {
+			typeArgs = append(typeArgs, named.TypeParams().At(i))
+		}
+
+		instance, err := types.Instantiate(nil, named, typeArgs, false)
+		if err != nil {
+			return leaveOut(err)
+		}
+
+		structRef = instance
+	}
+
+	structFields := structType.Underlying().(*types.Struct)
+
+	// Types are checked before resolving any of them, as resolving records imports.
+	if err := p.registry.CheckAccessible(structRef); err != nil {
+		return leaveOut(err)
+	}
+
+	if err := p.registry.CheckAccessible(structFields); err != nil {
+		return leaveOut(err)
+	}
+
+	_, structName, err := p.registry.Resolve(structRef)
+	if err != nil {
+		return leaveOut(err)
+	}
+
+	data := &template.DriftGuardData{
+		CommandName: commandName,
+		Name:        mirrorName(commandName),
+		Struct:      structName,
+		TypeParams:  p.typeParams,
+		TypeArgs:    p.typeArgs,
+		Fields:      make([]string, 0, structFields.NumFields()),
+	}
+
+	for i := 0; i < structFields.NumFields(); i++ {
+		field := structFields.Field(i)
+
+		pointer, fieldType, err := p.registry.Resolve(field.Type())
+		if err != nil {
+			return leaveOut(err)
+		}
+
+		if field.Embedded() {
+			data.Fields = append(data.Fields, pointer+fieldType)
+		} else {
+			data.Fields = append(data.Fields, fmt.Sprintf("%s %s%s", field.Name(), pointer, fieldType))
+		}
+	}
+
+	var b bytes.Buffer
+
+	if err := g.tpl.ExecuteDriftGuardTemplate(&b, data); err != nil {
+		return "", fmt.Errorf("failed to generate drift guard: %w", err)
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/build"
+	"path/filepath"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+	"golang.org/x/tools/go/packages"
+)
+
+const loadMode = packages.NeedName |
+	packages.NeedFiles |
+	packages.NeedSyntax |
+	packages.NeedTypes |
+	packages.NeedImports
+
+func newLoader(ctx context.Context, dir string) *loader {
+	return &loader{
+		config: &packages.Config{
+			Context: ctx,
+			Mode:    loadMode,
+			Dir:     dir,
+		},
+		packages: map[string]*packages.Package{},
+	}
+}
+
+type loader struct {
+	config   *packages.Config
+	packages map[string]*packages.Package
+}
+
+// Load loads all not yet loaded packages matching given patterns in a single run.
+func (l *loader) Load(patterns ...string) error {
+	var missing []string
+
+	for _, pattern := range patterns {
+		if _, ok := l.packages[pattern]; !ok {
+			missing = append(missing, pattern)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	pkgs, err := packages.Load(l.config, missing...)
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range missing {
+		for _, pkg := range pkgs {
+			if l.matches(pattern, pkg) {
+				l.packages[pattern] = pkg
+
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadAll loads all packages matching given patterns and caches them by their directories.
+func (l *loader) LoadAll(patterns ...string) ([]*packages.Package, error) {
+	pkgs, err := packages.Load(l.config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		if dir := packageDir(pkg); dir != "" {
+			l.packages[dir] = pkg
+		}
+	}
+
+	return pkgs, nil
+}
+
+// Package returns the package matching given pattern, loading it when needed.
+func (l *loader) Package(pattern string) (*packages.Package, error) {
+	if err := l.Load(pattern); err != nil {
+		return nil, err
+	}
+
+	pkg, ok := l.packages[pattern]
+	if !ok {
+		return nil, fmt.Errorf("package %s not found", pattern)
+	}
+	if len(pkg.Errors) > 0 {
+		return nil, packageErrors(pkg)
+	}
+
+	return pkg, nil
+}
+
+// TargetPackage returns the package matching given pattern, loading it when needed. Unlike Package,
+// it tolerates type-checking errors, as they may come from generated files, e.g. the previous version
+// of the one being generated. The package is type-checked again with the generated source.
+func (l *loader) TargetPackage(pattern string) (*packages.Package, error) {
+	if err := l.Load(pattern); err != nil {
+		return nil, err
+	}
+
+	pkg, ok := l.packages[pattern]
+	if !ok {
+		return nil, fmt.Errorf("package %s not found", pattern)
+	}
+
+	if !tolerableErrors(pkg) {
+		return nil, packageErrors(pkg)
+	}
+
+	return pkg, nil
+}
+
+// tolerableErrors reports whether the package has no errors or only type-checking errors, also reported by go list,
+// e.g. coming from generated files not regenerated yet after their source struct has changed.
+func tolerableErrors(pkg *packages.Package) bool {
+	if len(pkg.Errors) == 0 {
+		return true
+	}
+
+	if pkg.Types == nil || len(pkg.Syntax) == 0 {
+		return false
+	}
+
+	for _, pkgErr := range pkg.Errors {
+		if pkgErr.Kind == packages.ParseError || pkgErr.Kind == packages.UnknownError {
+			return false
+		}
+	}
+
+	return true
+}
+
+// packageErrors reports package's loading, parsing and type-checking errors, each at its position.
+// Errors reported by go list are skipped when parsing or type-checking errors repeat them with positions.
+func packageErrors(pkg *packages.Package) error {
+	errs := make([]error, 0, len(pkg.Errors))
+
+	located := false
+	for _, pkgErr := range pkg.Errors {
+		located = located || pkgErr.Kind != packages.ListError
+	}
+
+	for _, pkgErr := range pkg.Errors {
+		if located && pkgErr.Kind == packages.ListError {
+			continue
+		}
+
+		errs = append(errs, diagnostic.Errorf(diagnostic.ParsePosition(pkgErr.Pos), "package %s: %s", pkg.PkgPath, pkgErr.Msg))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l *loader) matches(pattern string, pkg *packages.Package) bool {
+	if !build.IsLocalImport(pattern) && !filepath.IsAbs(pattern) {
+		return pkg.PkgPath == pattern
+	}
+
+	dir := l.dir(pattern)
+
+	for _, files := range [][]string{pkg.GoFiles, pkg.CompiledGoFiles, pkg.OtherFiles} {
+		for _, file := range files {
+			if filepath.Dir(file) == dir {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (l *loader) dir(pattern string) string {
+	if filepath.IsAbs(pattern) {
+		return filepath.Clean(pattern)
+	}
+
+	return filepath.Join(l.config.Dir, pattern)
+}
+
+func packageDir(pkg *packages.Package) string {
+	for _, files := range [][]string{pkg.GoFiles, pkg.CompiledGoFiles, pkg.OtherFiles} {
+		if len(files) > 0 {
+			return filepath.Dir(files[0])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/donatorsky/go-cmder/internal/template"
+)
+
+const (
+	defaultSetterPrefix = "Set"
+	defaultHaserPrefix  = "Has"
+)
+
+// initialisms lists words written in upper case in Go identifiers, after golint's list.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "GUID": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true, "QPS": true,
+	"RAM": true, "RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true, "URL": true,
+	"UTF8": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// accessorName returns the name of the field of given key to use in the command's identifiers, e.g. in SetFoo
+// and vFoo: the field's rename, or its title-cased name, with initialisms upper-cased when enabled.
+func (c *Command) accessorName(key, name string) string {
+	if rename, ok := c.Renames[key]; ok {
+		return rename
+	}
+
+	words := splitWords(name)
+
+	// Names made of underscores only, e.g. of blank fields, have no words to upper-case.
+	if !c.Initialisms || len(words) == 0 {
+		return template.Title(name)
+	}
+
+	for i, word := range words {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			words[i] = upper
+		} else {
+			words[i] = template.Title(word)
+		}
+	}
+
+	return strings.Join(words, "")
+}
+
+func (c *Command) getterPrefix() string {
+	return c.GetterPrefix
+}
+
+func (c *Command) setterPrefix() string {
+	if c.SetterPrefix == "" {
+		return defaultSetterPrefix
+	}
+
+	return c.SetterPrefix
+}
+
+func (c *Command) haserPrefix() string {
+	if c.HaserPrefix == "" {
+		return defaultHaserPrefix
+	}
+
+	return c.HaserPrefix
+}
+
+// validateNaming checks that renames are identifiers and that accessors' prefixes make distinct identifiers.
+func (c *Command) validateNaming() error {
+	for _, key := range sortedKeys(c.Renames) {
+		if !token.IsIdentifier(c.Renames[key]) {
+			return fmt.Errorf("invalid rename of field %s: %q is not an identifier", key, c.Renames[key])
+		}
+	}
+
+	prefixes := map[string]string{}
+
+	for _, prefix := range []struct {
+		kind  string
+		value string
+	}{
+		{"getter", c.getterPrefix()},
+		{"setter", c.setterPrefix()},
+		{"haser", c.haserPrefix()},
+	} {
+		if prefix.value != "" && !token.IsIdentifier(prefix.value) {
+			return fmt.Errorf("invalid %s prefix %q: not an identifier", prefix.kind, prefix.value)
+		}
+
+		if prefix.value == "v" || prefix.value == "has" {
+			return fmt.Errorf("invalid %s prefix %q: reserved for the command's fields", prefix.kind, prefix.value)
+		}
+
+		if other, ok := prefixes[prefix.value]; ok {
+			return fmt.Errorf("%s and %s prefixes are the same: %q", other, prefix.kind, prefix.value)
+		}
+
+		prefixes[prefix.value] = prefix.kind
+	}
+
+	return nil
+}
+
+// splitWords splits camel- or snake-cased name into words, keeping runs of upper-case letters together
+// and dropping underscores, e.g. "userURLPath" and "user_url_path" into "user", "URL" and "Path" or "path".
+func splitWords(name string) []string {
+	var words []string
+
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' }) {
+		runes := []rune(part)
+		start := 0
+
+		for i := 1; i < len(runes); i++ {
+			previous, current := runes[i-1], runes[i]
+
+			lowerToUpper := unicode.IsUpper(current) && !unicode.IsUpper(previous)
+			endOfUpperRun := unicode.IsUpper(previous) && unicode.IsUpper(current) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if lowerToUpper || endOfUpperRun {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
@@ -0,0 +1,220 @@
+package generator
+
+import (
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+	}{
+		{
+			name:     "lower-case word",
+			value:    "id",
+			expected: []string{"id"},
+		},
+		{
+			name:     "initialism at the end",
+			value:    "userID",
+			expected: []string{"user", "ID"},
+		},
+		{
+			name:     "initialism at the beginning",
+			value:    "HTTPServer",
+			expected: []string{"HTTP", "Server"},
+		},
+		{
+			name:     "initialism in the middle",
+			value:    "userURLPath",
+			expected: []string{"user", "URL", "Path"},
+		},
+		{
+			name:     "snake-cased name",
+			value:    "url_path",
+			expected: []string{"url", "path"},
+		},
+		{
+			name:     "repeated and trailing underscores",
+			value:    "_url__path_",
+			expected: []string{"url", "path"},
+		},
+		{
+			name:  "underscores only",
+			value: "_",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := splitWords(tt.value); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("splitWords(%q) = %q, expected %q", tt.value, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAccessorName(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  Command
+		key      string
+		expected string
+	}{
+		{
+			name:     "without initialisms",
+			command:  Command{},
+			key:      "id",
+			expected: "Id",
+		},
+		{
+			name:     "lower-case initialism",
+			command:  Command{Initialisms: true},
+			key:      "id",
+			expected: "ID",
+		},
+		{
+			name:     "initialism at the end",
+			command:  Command{Initialisms: true},
+			key:      "userId",
+			expected: "UserID",
+		},
+		{
+			name:     "upper-case initialism is kept",
+			command:  Command{Initialisms: true},
+			key:      "userID",
+			expected: "UserID",
+		},
+		{
+			name:     "initialism at the beginning",
+			command:  Command{Initialisms: true},
+			key:      "HTTPServer",
+			expected: "HTTPServer",
+		},
+		{
+			name:     "snake-cased name",
+			command:  Command{Initialisms: true},
+			key:      "url_path",
+			expected: "URLPath",
+		},
+		{
+			name:     "snake-cased name without initialisms",
+			command:  Command{},
+			key:      "url_path",
+			expected: "Url_path",
+		},
+		{
+			name:     "underscores only",
+			command:  Command{Initialisms: true},
+			key:      "_",
+			expected: "_",
+		},
+		{
+			name:     "rename is used as is",
+			command:  Command{Initialisms: true, Renames: map[string]string{"id": "Identifier"}},
+			key:      "id",
+			expected: "Identifier",
+		},
+		{
+			name:     "rename of nested field",
+			command:  Command{Initialisms: true, Renames: map[string]string{"Address.id": "AddressID"}},
+			key:      "Address.id",
+			expected: "AddressID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := tt.key[strings.LastIndex(tt.key, ".")+1:]
+
+			if actual := tt.command.accessorName(tt.key, name); actual != tt.expected {
+				t.Errorf("accessorName(%q) = %q, expected %q", tt.key, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAccessorNameCollisions(t *testing.T) {
+	pkg := types.NewPackage("example.com/app", "app")
+
+	tests := []struct {
+		name     string
+		command  Command
+		fields   []string
+		expected string
+	}{
+		{
+			name:    "distinct accessors",
+			command: Command{Initialisms: true},
+			fields:  []string{"ID", "userID", "url_path"},
+		},
+		{
+			name:     "initialism colliding with upper-case field",
+			command:  Command{Initialisms: true},
+			fields:   []string{"ID", "id"},
+			expected: "field ID: both have accessor name ID",
+		},
+		{
+			name:     "snake-cased field colliding with camel-cased one",
+			command:  Command{Initialisms: true},
+			fields:   []string{"URLPath", "url_path"},
+			expected: "field URLPath: both have accessor name URLPath",
+		},
+		{
+			name:     "rename colliding with field",
+			command:  Command{Initialisms: true, Renames: map[string]string{"Identifier": "ID"}},
+			fields:   []string{"id", "Identifier"},
+			expected: "field id: both have accessor name ID",
+		},
+		{
+			name:     "rename differing only in case",
+			command:  Command{Renames: map[string]string{"Identifier": "ID"}},
+			fields:   []string{"id", "Identifier"},
+			expected: "field id: accessor names Id and ID differ only in case",
+		},
+		{
+			name:     "rename colliding with another field's method",
+			command:  Command{Renames: map[string]string{"Color": "Name"}},
+			fields:   []string{"SetName", "Color"},
+			expected: "field SetName: both generate SetName method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &plan{command: &tt.command}
+
+			accessors := map[string]*structField{}
+			methods := map[string]*structField{}
+
+			var actual string
+
+			for _, name := range tt.fields {
+				field := &structField{
+					field: types.NewField(token.NoPos, pkg, name, types.Typ[types.String], false),
+					path:  []string{name},
+				}
+				field.accessor = tt.command.accessorName(name, name)
+
+				if actual = p.collision(&commandNode{}, field, accessors, methods, nil); actual != "" {
+					break
+				}
+
+				accessors[strings.ToLower(field.accessor)] = field
+
+				for _, method := range p.accessorMethods(field) {
+					methods[method] = field
+				}
+			}
+
+			if actual != tt.expected {
+				t.Errorf("collision() = %q, expected %q", actual, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package generator
+
+import (
+	"errors"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+	"github.com/donatorsky/go-cmder/internal/template"
+	internalTypes "github.com/donatorsky/go-cmder/internal/types"
+	"golang.org/x/tools/go/packages"
+)
+
+// plan holds the state shared by the command and its nested commands while they are generated.
+type plan struct {
+	command   *Command
+	targetPkg *packages.Package
+	sourcePkg *packages.Package
+	registry  *internalTypes.Registry
+	tpl       *template.Template
+	include   []*fieldSelector
+	exclude   []*fieldSelector
+	// usedNested marks keys of Command.Nested matched with the struct's fields.
+	usedNested map[string]bool
+	// usedEmbedded marks keys of Command.Embedded matched with the struct's embedded fields.
+	usedEmbedded map[string]bool
+	// usedRenames marks keys of Command.Renames matched with the selected fields.
+	usedRenames map[string]bool
+	// fields lists fields of the command and nested commands rendered so far.
+	fields []*template.FieldData
+	// driftGuardWarning explains why the drift guard was left out, if it was.
+	driftGuardWarning error
+	// structPosition is the source struct declaration's position.
+	structPosition token.Position
+	// structType is the source struct, instantiated for generic structs with given type arguments.
+	structType *types.Struct
+
+	typeParams string
+	typeArgs   string
+}
+
+// commandNode is a command to generate, either the main command or a nested one.
+type commandNode struct {
+	name   string
+	source *template.SourceData
+	// path is the selector path of the nested command's field in the source struct, empty for the main command.
+	path   []string
+	fields []*structField
+	nested map[*structField]*commandNode
+}
+
+// planCommand selects the struct's fields, resolves their accessors' collisions and recursively plans
+// nested commands for the fields listed in Command.Nested, reserving identifiers of all of them.
+// Problems of all fields are reported together.
+func (p *plan) planCommand(name string, structType *types.Struct, source *structSource, keyPrefix string, path []string) (*commandNode, error) {
+	structFields, err := p.collectStructFields(structType, source, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	node := &commandNode{
+		name:   name,
+		path:   path,
+		nested: map[*structField]*commandNode{},
+	}
+
+	p.registry.Reserve(name, "New"+template.Title(name))
+
+	for _, structField := range structFields {
+		field := structField.field
+		key := keyPrefix + field.Name()
+		position := p.sourcePkg.Fset.Position(field.Pos())
+
+		if !p.selected(keyPrefix, key, structField) {
+			continue
+		}
+
+		if !field.Exported() {
+			if !p.command.IncludeUnexported {
+				continue
+			}
+
+			if field.Pkg() != nil && field.Pkg().Path() != p.targetPkg.PkgPath {
+				errs = append(errs, diagnostic.Errorf(position, "field %s is unexported and cannot be used outside of package %s", p.selector(path, structField), field.Pkg().Path()))
+
+				continue
+			}
+		}
+
+		if _, ok := p.command.Renames[key]; ok {
+			p.usedRenames[key] = true
+		}
+
+		structField.accessor = p.command.accessorName(key, field.Name())
+
+		node.fields = append(node.fields, structField)
+	}
+
+	if err := p.resolveCollisions(node); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, structField := range node.fields {
+		field := structField.field
+		key := keyPrefix + field.Name()
+		position := p.sourcePkg.Fset.Position(field.Pos())
+		fieldName := structField.accessor
+
+		p.registry.Reserve("v"+fieldName, "has"+fieldName)
+
+		nestedName, ok := p.command.Nested[key]
+		if !ok {
+			continue
+		}
+
+		p.usedNested[key] = true
+
+		fieldType := field.Type()
+		if pointer, ok := fieldType.(*types.Pointer); ok {
+			fieldType = pointer.Elem()
+		}
+
+		nestedStruct, ok := fieldType.Underlying().(*types.Struct)
+		if !ok {
+			errs = append(errs, diagnostic.Errorf(position, "field %s is not a struct and cannot have a nested command", p.selector(path, structField)))
+
+			continue
+		}
+
+		if nestedName == "" {
+			nestedName = name + fieldName
+		}
+
+		nestedSource := fieldStructSource(structField.syntax)
+		nestedSourceData := &template.SourceData{
+			Name: field.Name(),
+		}
+
+		if named, ok := fieldType.(*types.Named); ok && named.Obj().Pkg() != nil {
+			obj := named.Origin().Obj()
+
+			nestedSourceData.Name = obj.Name()
+			nestedSourceData.PackageName = obj.Pkg().Name()
+			nestedSourceData.PackagePath = obj.Pkg().Path()
+			nestedSourceData.Position = p.sourcePkg.Fset.Position(obj.Pos())
+
+			if obj.Pkg().Path() == p.sourcePkg.PkgPath {
+				nestedSource = findStructSource(p.sourcePkg, obj)
+			}
+		}
+
+		nestedSourceData.Doc = nestedSource.Doc()
+		nestedSourceData.BuildConstraint = nestedSource.BuildConstraint()
+
+		nestedNode, err := p.planCommand(nestedName, nestedStruct, nestedSource, key+".", append(path[:len(path):len(path)], structField.path...))
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		nestedNode.source = nestedSourceData
+		node.nested[structField] = nestedNode
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return node, nil
+}
+
+// selected reports whether the field of given key is included in the command. Include selectors
+// apply to the nesting level only when any of them applies to that level, and supersede exclude selectors.
+// All applicable selectors are evaluated, so the ones not matching any field can be reported.
+func (p *plan) selected(keyPrefix, key string, field *structField) bool {
+	includeApplied, included := evaluateSelectors(p.include, keyPrefix, key, field)
+	_, excluded := evaluateSelectors(p.exclude, keyPrefix, key, field)
+
+	if includeApplied {
+		return included
+	}
+
+	return !excluded
+}
+
+func evaluateSelectors(selectors []*fieldSelector, keyPrefix, key string, field *structField) (applied, matched bool) {
+	for _, selector := range selectors {
+		if !selector.appliesTo(keyPrefix) {
+			continue
+		}
+
+		applied = true
+
+		if selector.match(key, field) {
+			selector.matched = true
+			matched = true
+		}
+	}
+
+	return
+}
+
+// unusedKeys returns sorted keys of the command's option not matching any field, e.g. of Command.Nested.
+func unusedKeys[V any](option map[string]V, used map[string]bool) (keys []string) {
+	for key := range option {
+		if !used[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return
+}
+
+// warnings reports include and exclude selectors not matching any field and renames of fields not generated,
+// as they have no effect, and the drift guard left out.
+func (p *plan) warnings() (warnings []error) {
+	for _, list := range []struct {
+		name      string
+		selectors []*fieldSelector
+	}{
+		{"include", p.include},
+		{"exclude", p.exclude},
+	} {
+		for _, selector := range list.selectors {
+			if !selector.matched {
+				warnings = append(warnings, diagnostic.Warningf(p.command.Position, "%s selector %s does not match any field", list.name, selector.value))
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(p.command.Renames) {
+		if !p.usedRenames[key] {
+			warnings = append(warnings, diagnostic.Warningf(p.command.Position, "renamed field %s does not exist, is excluded or not included", key))
+		}
+	}
+
+	if p.driftGuardWarning != nil {
+		warnings = append(warnings, p.driftGuardWarning)
+	}
+
+	return
+}
+
+// position returns the position of problems of the whole command: its directive's, if any, or the source struct's,
+// e.g. for commands given as arguments or in the config file.
+func (p *plan) position() token.Position {
+	if p.command.Position.IsValid() {
+		return p.command.Position
+	}
+
+	return p.structPosition
+}
+
+// pointerSteps returns prefixes of the field path going through pointers in the source struct, outer first,
+// e.g. ["Base"] for ["Base", "Name"] path of a field promoted from embedded *Base.
+func (p *plan) pointerSteps(path []string) (steps []string) {
+	structType := p.structType
+
+	for i := 0; i < len(path)-1; i++ {
+		var fieldType types.Type
+
+		for j := 0; j < structType.NumFields(); j++ {
+			if structType.Field(j).Name() == path[i] {
+				fieldType = structType.Field(j).Type()
+
+				break
+			}
+		}
+
+		if fieldType == nil {
+			return
+		}
+
+		if pointer, ok := fieldType.(*types.Pointer); ok {
+			steps = append(steps, strings.Join(path[:i+1], "."))
+			fieldType = pointer.Elem()
+		}
+
+		var ok bool
+		if structType, ok = fieldType.Underlying().(*types.Struct); !ok {
+			return
+		}
+	}
+
+	return
+}
+
+func (p *plan) selector(path []string, structField *structField) string {
+	return strings.Join(append(path[:len(path):len(path)], structField.path...), ".")
+}
@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"fmt"
+	"go/types"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	regexpSelectorPrefix = "re:"
+	typeSelectorPrefix   = "type:"
+	tagSelectorPrefix    = "tag:"
+)
+
+// fieldSelector selects fields to include or exclude. It is one of:
+//
+//   - a field's name or dotted path, optionally with glob wildcards, e.g. Foo, *At or Address.Zip,
+//   - a regular expression matching the field's dotted path, e.g. re:^(Created|Updated)At$,
+//   - a type selector matching the field's type or its kind, e.g. type:sync.Mutex, type:*time.Time or type:func,
+//   - a tag selector matching fields having the tag key, e.g. tag:db, or having given tag value, e.g. tag:json=-.
+//
+// Names, paths and globs apply to a single nesting level, the other selectors apply to all of them.
+type fieldSelector struct {
+	value string
+	// anyLevel tells whether the selector applies to all nesting levels.
+	anyLevel bool
+	// parents are the glob patterns of the nesting level the selector applies to, e.g. ["Address"].
+	parents []string
+	match   func(key string, field *structField) bool
+	matched bool
+}
+
+// typeKinds lists kinds type selectors match by the field type's underlying type.
+var typeKinds = map[string]func(t types.Type) bool{
+	"func":      func(t types.Type) bool { _, ok := t.(*types.Signature); return ok },
+	"chan":      func(t types.Type) bool { _, ok := t.(*types.Chan); return ok },
+	"map":       func(t types.Type) bool { _, ok := t.(*types.Map); return ok },
+	"slice":     func(t types.Type) bool { _, ok := t.(*types.Slice); return ok },
+	"array":     func(t types.Type) bool { _, ok := t.(*types.Array); return ok },
+	"pointer":   func(t types.Type) bool { _, ok := t.(*types.Pointer); return ok },
+	"struct":    func(t types.Type) bool { _, ok := t.(*types.Struct); return ok },
+	"interface": func(t types.Type) bool { _, ok := t.(*types.Interface); return ok },
+}
+
+// parseSelectors parses comma-separated lists of selectors. A regular expression takes the rest of its list,
+// so it can contain commas. Types are matched as written in the struct's package, with package names.
+func parseSelectors(lists []string, pkg *types.Package) ([]*fieldSelector, error) {
+	var selectors []*fieldSelector
+
+	for _, list := range lists {
+		for list != "" {
+			value := list
+			list = ""
+
+			if !strings.HasPrefix(value, regexpSelectorPrefix) {
+				value, list, _ = strings.Cut(value, ",")
+			}
+
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			selector, err := parseSelector(value, pkg)
+			if err != nil {
+				return nil, err
+			}
+
+			selectors = append(selectors, selector)
+		}
+	}
+
+	return selectors, nil
+}
+
+func parseSelector(value string, pkg *types.Package) (*fieldSelector, error) {
+	selector := &fieldSelector{
+		value:    value,
+		anyLevel: true,
+	}
+
+	switch {
+	case strings.HasPrefix(value, regexpSelectorPrefix):
+		pattern, err := regexp.Compile(strings.TrimPrefix(value, regexpSelectorPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid field selector %q: %w", value, err)
+		}
+
+		selector.match = func(key string, _ *structField) bool {
+			return pattern.MatchString(key)
+		}
+
+	case strings.HasPrefix(value, typeSelectorPrefix):
+		typeName := strings.TrimPrefix(value, typeSelectorPrefix)
+		if typeName == "" {
+			return nil, fmt.Errorf("invalid field selector %q: missing type", value)
+		}
+
+		qualifier := func(other *types.Package) string {
+			if other == pkg {
+				return ""
+			}
+
+			return other.Name()
+		}
+
+		isKind := typeKinds[typeName]
+
+		selector.match = func(_ string, field *structField) bool {
+			if isKind != nil {
+				return isKind(field.field.Type().Underlying())
+			}
+
+			return types.TypeString(field.field.Type(), qualifier) == typeName
+		}
+
+	case strings.HasPrefix(value, tagSelectorPrefix):
+		key, tagValue, hasValue := strings.Cut(strings.TrimPrefix(value, tagSelectorPrefix), "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid field selector %q: missing tag key", value)
+		}
+
+		selector.match = func(_ string, field *structField) bool {
+			actual, ok := reflect.StructTag(field.tag).Lookup(key)
+			if !ok || !hasValue {
+				return ok
+			}
+
+			name, _, _ := strings.Cut(actual, ",")
+
+			return actual == tagValue || name == tagValue
+		}
+
+	default:
+		segments := strings.Split(value, ".")
+
+		for _, segment := range segments {
+			if _, err := path.Match(segment, ""); err != nil || segment == "" {
+				return nil, fmt.Errorf("invalid field selector %q: malformed pattern", value)
+			}
+		}
+
+		name := segments[len(segments)-1]
+		selector.anyLevel = false
+		selector.parents = segments[:len(segments)-1]
+
+		selector.match = func(key string, _ *structField) bool {
+			matched, _ := path.Match(name, key[strings.LastIndex(key, ".")+1:])
+
+			return matched
+		}
+	}
+
+	return selector, nil
+}
+
+// appliesTo reports whether the selector applies to fields of the nesting level of given key prefix, e.g. "Address.".
+func (s *fieldSelector) appliesTo(keyPrefix string) bool {
+	if s.anyLevel {
+		return true
+	}
+
+	var parents []string
+	if keyPrefix != "" {
+		parents = strings.Split(strings.TrimSuffix(keyPrefix, "."), ".")
+	}
+
+	if len(parents) != len(s.parents) {
+		return false
+	}
+
+	for i, pattern := range s.parents {
+		if matched, _ := path.Match(pattern, parents[i]); !matched {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,286 @@
+package generator
+
+import (
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestParseSelectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		lists    []string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "comma-separated lists",
+			lists:    []string{"Foo, Bar", "*At"},
+			expected: []string{"Foo", "Bar", "*At"},
+		},
+		{
+			name:     "blank selectors are skipped",
+			lists:    []string{"Foo,,", ""},
+			expected: []string{"Foo"},
+		},
+		{
+			name:     "regular expression takes the rest of its list",
+			lists:    []string{"Foo,re:^(A|B),C$"},
+			expected: []string{"Foo", "re:^(A|B),C$"},
+		},
+		{
+			name:    "invalid regular expression",
+			lists:   []string{"re:(Foo"},
+			wantErr: true,
+		},
+		{
+			name:    "type selector without type",
+			lists:   []string{"type:"},
+			wantErr: true,
+		},
+		{
+			name:    "tag selector without key",
+			lists:   []string{"tag:=-"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed glob",
+			lists:   []string{"Foo["},
+			wantErr: true,
+		},
+		{
+			name:    "empty path segment",
+			lists:   []string{"Address..Zip"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selectors, err := parseSelectors(tt.lists, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseSelectors() expected error, got %d selectors", len(selectors))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseSelectors() unexpected error: %v", err)
+			}
+
+			if len(selectors) != len(tt.expected) {
+				t.Fatalf("parseSelectors() returned %d selectors, expected %d", len(selectors), len(tt.expected))
+			}
+
+			for i, selector := range selectors {
+				if selector.value != tt.expected[i] {
+					t.Errorf("selector %d = %q, expected %q", i, selector.value, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFieldSelector(t *testing.T) {
+	pkg := types.NewPackage("example.com/app", "app")
+	timePkg := types.NewPackage("time", "time")
+
+	timeType := types.NewNamed(types.NewTypeName(token.NoPos, timePkg, "Time", nil), types.NewStruct(nil, nil), nil)
+	statusType := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Status", nil), types.Typ[types.String], nil)
+	funcType := types.NewSignatureType(nil, nil, nil, nil, nil, false)
+
+	field := func(name string, fieldType types.Type, tag string) *structField {
+		return &structField{
+			field: types.NewField(token.NoPos, pkg, name, fieldType, false),
+			tag:   tag,
+			path:  []string{name},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		selector  string
+		keyPrefix string
+		key       string
+		field     *structField
+		applies   bool
+		matches   bool
+	}{
+		{
+			name:     "glob at the top level",
+			selector: "*At",
+			key:      "CreatedAt",
+			field:    field("CreatedAt", timeType, ""),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:      "glob does not apply to nested fields",
+			selector:  "*At",
+			keyPrefix: "Address.",
+			key:       "Address.CreatedAt",
+			field:     field("CreatedAt", timeType, ""),
+			matches:   true,
+		},
+		{
+			name:      "glob on nested path",
+			selector:  "Address.Z*",
+			keyPrefix: "Address.",
+			key:       "Address.Zip",
+			field:     field("Zip", types.Typ[types.String], ""),
+			applies:   true,
+			matches:   true,
+		},
+		{
+			name:      "glob on nested path not matching the name",
+			selector:  "Address.Z*",
+			keyPrefix: "Address.",
+			key:       "Address.City",
+			field:     field("City", types.Typ[types.String], ""),
+			applies:   true,
+		},
+		{
+			name:     "glob on nested path does not apply to the top level",
+			selector: "Address.Z*",
+			key:      "Zip",
+			field:    field("Zip", types.Typ[types.String], ""),
+			matches:  true,
+		},
+		{
+			name:      "glob on nested path does not apply to deeper levels",
+			selector:  "Address.Z*",
+			keyPrefix: "Address.Geo.",
+			key:       "Address.Geo.Zip",
+			field:     field("Zip", types.Typ[types.String], ""),
+			matches:   true,
+		},
+		{
+			name:      "glob in parent segment",
+			selector:  "*.Zip",
+			keyPrefix: "Billing.",
+			key:       "Billing.Zip",
+			field:     field("Zip", types.Typ[types.String], ""),
+			applies:   true,
+			matches:   true,
+		},
+		{
+			name:      "regular expression matches the path at any level",
+			selector:  `re:^Address\.Z`,
+			keyPrefix: "Address.",
+			key:       "Address.Zip",
+			field:     field("Zip", types.Typ[types.String], ""),
+			applies:   true,
+			matches:   true,
+		},
+		{
+			name:     "regular expression not matching the path",
+			selector: `re:^Address\.Z`,
+			key:      "Zip",
+			field:    field("Zip", types.Typ[types.String], ""),
+			applies:  true,
+		},
+		{
+			name:     "type:func matches function fields",
+			selector: "type:func",
+			key:      "OnSave",
+			field:    field("OnSave", funcType, ""),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:     "type:func does not match other fields",
+			selector: "type:func",
+			key:      "Name",
+			field:    field("Name", types.Typ[types.String], ""),
+			applies:  true,
+		},
+		{
+			name:     "type:* matches pointer type",
+			selector: "type:*time.Time",
+			key:      "DeletedAt",
+			field:    field("DeletedAt", types.NewPointer(timeType), ""),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:     "type:* does not match the pointed type",
+			selector: "type:*time.Time",
+			key:      "CreatedAt",
+			field:    field("CreatedAt", timeType, ""),
+			applies:  true,
+		},
+		{
+			name:     "type:pointer matches pointer kind",
+			selector: "type:pointer",
+			key:      "DeletedAt",
+			field:    field("DeletedAt", types.NewPointer(timeType), ""),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:     "type of the struct's package is unqualified",
+			selector: "type:Status",
+			key:      "Status",
+			field:    field("Status", statusType, ""),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:     "tag:json=- matches skipped fields",
+			selector: "tag:json=-",
+			key:      "Secret",
+			field:    field("Secret", types.Typ[types.String], `json:"-"`),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:     "tag:json=- does not match named fields",
+			selector: "tag:json=-",
+			key:      "Name",
+			field:    field("Name", types.Typ[types.String], `json:"name"`),
+			applies:  true,
+		},
+		{
+			name:     "tag:json=name matches the tag's name",
+			selector: "tag:json=name",
+			key:      "Name",
+			field:    field("Name", types.Typ[types.String], `json:"name,omitempty"`),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:     "tag:json matches any json tag",
+			selector: "tag:json",
+			key:      "Secret",
+			field:    field("Secret", types.Typ[types.String], `json:"-"`),
+			applies:  true,
+			matches:  true,
+		},
+		{
+			name:     "tag:json does not match fields without json tag",
+			selector: "tag:json",
+			key:      "ID",
+			field:    field("ID", types.Typ[types.String], `db:"id"`),
+			applies:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := parseSelector(tt.selector, pkg)
+			if err != nil {
+				t.Fatalf("parseSelector() unexpected error: %v", err)
+			}
+
+			if applies := selector.appliesTo(tt.keyPrefix); applies != tt.applies {
+				t.Errorf("appliesTo(%q) = %t, expected %t", tt.keyPrefix, applies, tt.applies)
+			}
+
+			if matches := selector.match(tt.key, tt.field); matches != tt.matches {
+				t.Errorf("match(%q) = %t, expected %t", tt.key, matches, tt.matches)
+			}
+		})
+	}
+}
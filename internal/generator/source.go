@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go/ast"
+	"go/build/constraint"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// structSource is the syntax of a struct declaration.
+type structSource struct {
+	file       *ast.File
+	doc        *ast.CommentGroup
+	structType *ast.StructType
+}
+
+// findStructSource finds the declaration of given type name in package's syntax trees.
+func findStructSource(pkg *packages.Package, obj types.Object) *structSource {
+	for _, file := range pkg.Syntax {
+		if obj.Pos() < file.Pos() || obj.Pos() >= file.End() {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Name.Pos() != obj.Pos() {
+					continue
+				}
+
+				doc := typeSpec.Doc
+				if doc == nil && !genDecl.Lparen.IsValid() {
+					doc = genDecl.Doc
+				}
+
+				structType, _ := typeSpec.Type.(*ast.StructType)
+
+				return &structSource{
+					file:       file,
+					doc:        doc,
+					structType: structType,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// fieldStructSource returns the syntax of a struct literal used as a field's type, e.g. struct{ Foo string }
+// or *struct{ Foo string }.
+func fieldStructSource(field *ast.Field) *structSource {
+	if field == nil {
+		return nil
+	}
+
+	expr := field.Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+
+	structType, ok := expr.(*ast.StructType)
+	if !ok {
+		return nil
+	}
+
+	return &structSource{
+		structType: structType,
+	}
+}
+
+// Doc returns the struct's doc comment text.
+func (s *structSource) Doc() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.doc.Text()
+}
+
+// BuildConstraint returns the //go:build expression of the file declaring the struct.
+func (s *structSource) BuildConstraint() string {
+	if s == nil || s.file == nil {
+		return ""
+	}
+
+	for _, group := range s.file.Comments {
+		if group.Pos() >= s.file.Package {
+			break
+		}
+
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+
+			return expr.String()
+		}
+	}
+
+	return ""
+}
+
+// Field returns the syntax of the field declared at given position.
+func (s *structSource) Field(pos token.Pos) *ast.Field {
+	if s == nil || s.structType == nil {
+		return nil
+	}
+
+	for _, field := range s.structType.Fields.List {
+		if field.Pos() <= pos && pos < field.End() {
+			return field
+		}
+	}
+
+	return nil
+}
+
+// parseTags parses struct tag into key-value pairs, following reflect.StructTag conventions.
+func parseTags(tag string) map[string]string {
+	tags := map[string]string{}
+
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+
+		i := strings.Index(tag, ":")
+		if i <= 0 || i+1 >= len(tag) || tag[i+1] != '"' || strings.ContainsAny(tag[:i], " \"") {
+			break
+		}
+
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		j := 1
+		for j < len(tag) && tag[j] != '"' {
+			if tag[j] == '\\' {
+				j++
+			}
+
+			j++
+		}
+
+		if j >= len(tag) {
+			break
+		}
+
+		value, err := strconv.Unquote(tag[:j+1])
+		if err != nil {
+			break
+		}
+
+		tags[key] = value
+		tag = tag[j+1:]
+	}
+
+	return tags
+}
+
+// sourceHash returns a hash of the struct's definition: its fields' names, types and tags.
+// It does not depend on formatting or comments of the struct's declaration.
+func sourceHash(structType types.Type) string {
+	definition := types.TypeString(structType.Underlying(), (*types.Package).Path)
+	sum := sha256.Sum256([]byte(definition))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/internal/template"
+)
+
+// Struct is a struct declared in a package, commands can be generated from.
+type Struct struct {
+	Name        string
+	PackagePath string
+	// TypeParams is the struct's type parameter list, e.g. "[T any]", or an empty string.
+	TypeParams string
+	Position   token.Position
+	Fields     []StructField
+}
+
+// StructField is a field of a Struct. Fields of embedded structs are not flattened.
+type StructField struct {
+	Name     string
+	Type     string
+	Tag      string
+	Embedded bool
+	Exported bool
+	Position token.Position
+}
+
+// Structs loads packages matching given patterns and lists structs declared in them, sorted by name
+// within every package. Structs declared in generated files are skipped. Types are written
+// with package names, relative to the struct's package.
+func (g *Generator) Structs(patterns ...string) ([]Struct, error) {
+	pkgs, err := g.loader.LoadAll(patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load packages: %w", err)
+	}
+
+	var (
+		structs []Struct
+		errs    []error
+	)
+
+	for _, pkg := range pkgs {
+		if !tolerableErrors(pkg) {
+			errs = append(errs, packageErrors(pkg))
+
+			continue
+		}
+
+		qualifier := func(other *types.Package) string {
+			if other == pkg.Types {
+				return ""
+			}
+
+			return other.Name()
+		}
+
+		generatedFiles := map[string]bool{}
+
+		for _, file := range pkg.Syntax {
+			if isGeneratedSyntax(file) {
+				generatedFiles[pkg.Fset.Position(file.Package).Filename] = true
+			}
+		}
+
+		scope := pkg.Types.Scope()
+
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+
+			structType, ok := obj.Type().Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+
+			position := pkg.Fset.Position(obj.Pos())
+			if generatedFiles[position.Filename] {
+				continue
+			}
+
+			s := Struct{
+				Name:        name,
+				PackagePath: pkg.PkgPath,
+				Position:    position,
+				Fields:      make([]StructField, 0, structType.NumFields()),
+			}
+
+			if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				typeParams := make([]string, 0, named.TypeParams().Len())
+
+				for i := 0; i < named.TypeParams().Len(); i++ {
+					typeParam := named.TypeParams().At(i)
+					typeParams = append(typeParams, fmt.Sprintf("%s %s", typeParam.Obj().Name(), types.TypeString(typeParam.Constraint(), qualifier)))
+				}
+
+				s.TypeParams = fmt.Sprintf("[%s]", strings.Join(typeParams, ", "))
+			}
+
+			for i := 0; i < structType.NumFields(); i++ {
+				field := structType.Field(i)
+
+				s.Fields = append(s.Fields, StructField{
+					Name:     field.Name(),
+					Type:     types.TypeString(field.Type(), qualifier),
+					Tag:      structType.Tag(i),
+					Embedded: field.Embedded(),
+					Exported: field.Exported(),
+					Position: pkg.Fset.Position(field.Pos()),
+				})
+			}
+
+			structs = append(structs, s)
+		}
+	}
+
+	return structs, errors.Join(errs...)
+}
+
+// isGeneratedSyntax reports whether the file starts with template.GeneratedHeader.
+func isGeneratedSyntax(file *ast.File) bool {
+	return len(file.Comments) > 0 && file.Comments[0].Pos() < file.Package && file.Comments[0].List[0].Text == template.GeneratedHeader
+}
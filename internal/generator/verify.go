@@ -0,0 +1,286 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+
+	"github.com/donatorsky/go-cmder/internal/diagnostic"
+	"github.com/donatorsky/go-cmder/internal/template"
+	"golang.org/x/tools/go/packages"
+)
+
+// verifySource checks that declarations of the generated source do not conflict with the ones of the target package
+// and type-checks it together with the rest of the package, skipping the previous version of the generated file.
+// Sources of other commands generated at once are given by their output paths in batch: they replace
+// previous versions of their files, so the package may refer to commands not generated before.
+// Problems found in the generated source are reported each at the source struct field the offending code
+// was generated from, when known. Problems found in the rest of the package are reported at their positions,
+// except for other generated files, e.g. commands not regenerated yet after their source struct has changed.
+// A missing Validate method called by validate constructors is reported instead of the errors of the generated source.
+func verifySource(p *plan, filename string, src []byte, batch map[string][]byte) error {
+	fset := p.targetPkg.Fset
+
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("generated source does not parse: %w", err)
+	}
+
+	var (
+		others         []*ast.File
+		generatedFiles = map[string]bool{}
+		replaced       = map[string]bool{filepath.Clean(filename): true}
+	)
+
+	for _, path := range sortedKeys(batch) {
+		if filepath.Clean(path) == filepath.Clean(filename) {
+			continue
+		}
+
+		other, err := parser.ParseFile(fset, path, batch[path], parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("generated source of %s does not parse: %w", path, err)
+		}
+
+		others = append(others, other)
+		generatedFiles[path] = true
+		replaced[filepath.Clean(path)] = true
+	}
+
+	for _, syntax := range p.targetPkg.Syntax {
+		if !replaced[filepath.Clean(fset.Position(syntax.Package).Filename)] {
+			others = append(others, syntax)
+		}
+
+		if isGeneratedSyntax(syntax) {
+			generatedFiles[fset.Position(syntax.Package).Filename] = true
+		}
+	}
+
+	if err := checkDeclarations(p, file, others); err != nil {
+		return err
+	}
+
+	var (
+		typeErrors []types.Error
+		errs       []error
+	)
+
+	config := types.Config{
+		Importer:    importerFunc(p.importPackage),
+		FakeImportC: true,
+		Error: func(err error) {
+			var typeError types.Error
+			if !errors.As(err, &typeError) {
+				return
+			}
+
+			switch position := fset.Position(typeError.Pos); {
+			case position.Filename == filename:
+				typeErrors = append(typeErrors, typeError)
+			case !generatedFiles[position.Filename]:
+				errs = append(errs, diagnostic.Errorf(position, "package %s: %s", p.targetPkg.PkgPath, typeError.Msg))
+			}
+		},
+	}
+
+	pkg, _ := config.Check(p.targetPkg.PkgPath, fset, append([]*ast.File{file}, others...), nil)
+
+	if p.command.validates() {
+		if err := checkValidateMethod(p, pkg); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+	}
+
+	for _, typeError := range typeErrors {
+		position := fset.Position(typeError.Pos)
+
+		if field := p.fieldAt(file, typeError.Pos); field != nil {
+			errs = append(errs, diagnostic.Errorf(field.Position, "generated code of field %s does not compile: %s: %s", field.Path, position, typeError.Msg))
+		} else {
+			errs = append(errs, diagnostic.Errorf(position, "generated source does not compile: %s", typeError.Msg))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkValidateMethod reports the command type of the type-checked package missing Validate() error method,
+// called by validate constructors. It has to be declared in a hand-written file of the package.
+func checkValidateMethod(p *plan, pkg *types.Package) error {
+	typeName, ok := pkg.Scope().Lookup(p.command.Name).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+
+	obj, _, _ := types.LookupFieldOrMethod(typeName.Type(), true, pkg, validateMethod)
+
+	method, ok := obj.(*types.Func)
+	if !ok {
+		return diagnostic.Errorf(p.position(), "validate constructors call %s() error method of %s, declare it in package %s", validateMethod, p.command.Name, p.targetPkg.PkgPath)
+	}
+
+	signature := method.Type().(*types.Signature)
+	if signature.Params().Len() != 0 || signature.Results().Len() != 1 || !types.Identical(signature.Results().At(0).Type(), types.Universe.Lookup("error").Type()) {
+		return diagnostic.Errorf(p.targetPkg.Fset.Position(method.Pos()), "%s.%s method has signature %s, validate constructors expect func() error", p.command.Name, validateMethod, signature)
+	}
+
+	return nil
+}
+
+// checkDeclarations reports declarations of the generated source already declared in other files of the target package:
+// package-level identifiers, e.g. the command type or its constructors, and methods and fields of the command types,
+// e.g. a hand-written method named like a setter. Every conflict is reported at the existing declaration,
+// with the position of the generated one.
+func checkDeclarations(p *plan, file *ast.File, others []*ast.File) error {
+	fset := p.targetPkg.Fset
+	existing := map[string]*ast.Ident{}
+
+	for _, other := range others {
+		declarations(other, func(key string, ident *ast.Ident) {
+			if _, ok := existing[key]; !ok {
+				existing[key] = ident
+			}
+		})
+	}
+
+	var errs []error
+
+	declarations(file, func(key string, ident *ast.Ident) {
+		if other, ok := existing[key]; ok {
+			errs = append(errs, diagnostic.Errorf(fset.Position(other.Pos()), "%s is already declared, conflicts with the one generated at %s", key, fset.Position(ident.Pos())))
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// declarations calls add for every package-level identifier declared in the file, e.g. "Cmd",
+// and every method and struct field of the file's types, e.g. "Cmd.SetFoo".
+func declarations(file *ast.File, add func(key string, ident *ast.Ident)) {
+	addAll := func(prefix string, idents []*ast.Ident) {
+		for _, ident := range idents {
+			if ident.Name != "_" {
+				add(prefix+ident.Name, ident)
+			}
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				if d.Name.Name != "init" {
+					addAll("", []*ast.Ident{d.Name})
+				}
+			} else if typeName := receiverTypeName(d.Recv.List[0].Type); typeName != "" {
+				addAll(typeName+".", []*ast.Ident{d.Name})
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					addAll("", []*ast.Ident{s.Name})
+
+					if structType, ok := s.Type.(*ast.StructType); ok {
+						for _, field := range structType.Fields.List {
+							addAll(s.Name.Name+".", field.Names)
+						}
+					}
+
+				case *ast.ValueSpec:
+					addAll("", s.Names)
+				}
+			}
+		}
+	}
+}
+
+// receiverTypeName returns the name of the method receiver's base type, e.g. Cmd for *Cmd[T].
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// importPackage returns the package of given path, preferring the instances generated types refer to.
+func (p *plan) importPackage(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	if pkg := p.registry.Package(path); pkg != nil {
+		return pkg, nil
+	}
+
+	for _, pkg := range []*packages.Package{p.targetPkg, p.sourcePkg} {
+		if path == pkg.PkgPath {
+			return pkg.Types, nil
+		}
+
+		if imported, ok := pkg.Imports[path]; ok && imported.Types != nil {
+			return imported.Types, nil
+		}
+	}
+
+	return nil, fmt.Errorf("package %s not found", path)
+}
+
+// fieldAt returns the source struct field the innermost declaration at given position was generated from.
+// The declaration is matched by its name, e.g. vFoo, hasFoo, or the field's getter, setter or haser.
+func (p *plan) fieldAt(file *ast.File, pos token.Pos) *template.FieldData {
+	var names []string
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		if node == nil || pos < node.Pos() || pos >= node.End() {
+			return false
+		}
+
+		switch n := node.(type) {
+		case *ast.FuncDecl:
+			names = append(names, n.Name.Name)
+
+		case *ast.Field:
+			for _, name := range n.Names {
+				names = append(names, name.Name)
+			}
+		}
+
+		return true
+	})
+
+	for i := len(names) - 1; i >= 0; i-- {
+		for _, field := range p.fields {
+			switch names[i] {
+			case "v" + field.AccessorName, "has" + field.AccessorName, field.Getter, field.Setter, field.Haser:
+				return field
+			}
+		}
+	}
+
+	return nil
+}
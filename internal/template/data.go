@@ -1,37 +1,152 @@
 package template
 
 import (
+	"go/token"
+	"reflect"
 	"strings"
 
 	internalTypes "github.com/donatorsky/go-cmder/internal/types"
 )
 
+// CommandData, FieldData and ConstructorData are the data passed to the templates.
+// They are a stable contract for user-defined templates: fields are only ever added,
+// never renamed or removed.
+
+// CommandData is passed to the command and nested templates and to the additional per-command templates.
 type CommandData struct {
-	PackageName  string
-	Imports      []*internalTypes.Type
-	CommandName  string
-	Fields       []*FieldData
+	// PackageName is the name of the package the command is generated in.
+	PackageName string
+	// Imports lists packages used by the command's fields.
+	Imports []*internalTypes.Type
+	// CommandName is the name of the generated command type.
+	CommandName string
+	// TypeParams is the command's type parameter list, e.g. "[T any]", empty for non-generic commands.
+	TypeParams string
+	// TypeArgs is the command's type parameter names to use in its receivers, e.g. "[T]".
+	TypeArgs string
+	// Fields lists the command's fields in the generation order.
+	Fields []*FieldData
+	// Constructors holds already rendered constructors.
 	Constructors []string
-	Methods      []string
+	// Methods holds already rendered methods: getters, setters, hasers and the additional templates' outputs.
+	Methods []string
+	// Source describes the struct the command is generated from.
+	Source *SourceData
+	// Nested holds already rendered nested commands, empty for nested commands themselves.
+	Nested []string
+	// Invocation is the go-cmder command line generating the command, empty for nested commands.
+	Invocation string
+	// SourceHash is the hash of the source struct's definition, empty for nested commands.
+	SourceHash string
+	// DriftGuard holds already rendered declarations breaking the build when the source struct's fields change,
+	// empty for nested commands and when the struct cannot be mirrored in the command's package.
+	DriftGuard string
+}
+
+// SourceData describes the struct a command is generated from.
+type SourceData struct {
+	// Name is the struct's name.
+	Name string
+	// PackageName is the name of the package declaring the struct.
+	PackageName string
+	// PackagePath is the import path of the package declaring the struct.
+	PackagePath string
+	// Doc is the struct's doc comment text.
+	Doc string
+	// BuildConstraint is the //go:build expression of the file declaring the struct, empty when there is none.
+	BuildConstraint string
+	// Position is the struct declaration's position.
+	Position token.Position
 }
 
+// FieldData is passed to the getter, setter and haser templates and to the additional per-field templates.
 type FieldData struct {
+	// CommandName is the name of the generated command type.
 	CommandName string
-	Mutable     bool
-	Name        string
-	Pointer     string
-	Type        string
+	// TypeParams is the command's type parameter list, e.g. "[T any]", empty for non-generic commands.
+	TypeParams string
+	// TypeArgs is the command's type parameter names to use in its receivers, e.g. "[T]".
+	TypeArgs string
+	// Mutable reports whether the command is mutable, i.e. has pointer receivers.
+	Mutable bool
+	// Name is the source struct field's name.
+	Name string
+	// Path is the field's selector in the source struct, e.g. "Base.Name" for a field promoted from flattened Base.
+	Path string
+	// PointerSteps lists prefixes of Path going through pointers, outer first, e.g. ["Base"] for "Base.Name" promoted
+	// from flattened *Base. They are nil in a zero source struct, so code setting the field through Path has to allocate them first.
+	PointerSteps []string
+	// AccessorName is the field's name in the command's identifiers, e.g. "ID" in vID and SetID for "id" field
+	// with initialisms enabled, or the field's rename.
+	AccessorName string
+	// Getter, Setter and Haser are the names of the field's methods, e.g. "GetID", "WithID" and "IsSetID".
+	Getter string
+	Setter string
+	Haser  string
+	// Pointer is the field type's pointer prefix, e.g. "**" for **string.
+	Pointer string
+	// Type is the field's type without the pointer prefix, qualified with the package aliases from Imports.
+	Type string
+	// NestedCommand is the name of the nested command the field holds, empty for regular fields.
+	NestedCommand string
+	// Kind classifies the field's type.
+	Kind internalTypes.Kind
+	// Tag is the field's raw struct tag. Use {{ .Tag.Get "json" }} to get a single value.
+	Tag reflect.StructTag
+	// Tags maps the field's struct tag keys to their values.
+	Tags map[string]string
+	// Doc is the field's doc comment text.
+	Doc string
+	// Comment is the field's line comment text.
+	Comment string
+	// Deprecated is the paragraph of the field's doc or line comment starting with "Deprecated:", empty when there is none.
+	Deprecated string
+	// Position is the source struct field's position.
+	Position token.Position
+	// Embedded reports whether the field is an embedded one.
+	Embedded bool
+	// Exported reports whether the field is exported.
+	Exported bool
 }
 
 func (c *FieldData) UniqueValue() any {
-	return strings.ToLower(c.Name)
+	return strings.ToLower(c.AccessorName)
+}
+
+// DriftGuardData is passed to the drift guard template.
+type DriftGuardData struct {
+	// CommandName is the name of the generated command type.
+	CommandName string
+	// Name is the name of the source struct's mirror, e.g. "createUserCmdSource".
+	Name string
+	// Struct is the source struct's type, qualified with the package alias from Imports, e.g. "domain.User" or "Page[T]".
+	Struct string
+	// TypeParams is the command's type parameter list, e.g. "[T any]", empty for non-generic commands.
+	TypeParams string
+	// TypeArgs is the command's type parameter names, e.g. "[T]".
+	TypeArgs string
+	// Fields lists the source struct's field declarations, e.g. "Name string" or "*Base" for an embedded field.
+	Fields []string
 }
 
+// ConstructorData is passed to the constructor template.
 type ConstructorData struct {
+	// CommandName is the name of the generated command type.
 	CommandName string
-	Mutable     bool
-	Name        string
-	Fields      []*FieldData
+	// TypeParams is the command's type parameter list, e.g. "[T any]", empty for non-generic commands.
+	TypeParams string
+	// TypeArgs is the command's type parameter names to use in its receivers, e.g. "[T]".
+	TypeArgs string
+	// Mutable reports whether the command is mutable, i.e. the constructor returns a pointer.
+	Mutable bool
+	// Name is the constructor's name without the "New" prefix.
+	Name string
+	// Fields lists the constructor's parameters.
+	Fields []*FieldData
+	// Validate reports whether the constructor returns (Cmd, error), the result of the command's Validate() error method.
+	Validate bool
+	// Ptr reports whether the constructor takes values for pointer fields and stores pointers to them.
+	Ptr bool
 }
 
 func (c *ConstructorData) UniqueValue() any {
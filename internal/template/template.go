@@ -1,21 +1,41 @@
 package template
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 )
 
+const templateExtension = ".tmpl"
+
+// GeneratedHeader is the first line of files generated with the default command template.
+const GeneratedHeader = "// Code generated by go-cmder; DO NOT EDIT."
+
 const (
-	commandTemplate = `package {{ .PackageName }}
+	commandTemplate = GeneratedHeader + `
+// Invocation: {{ .Invocation }}
+// Source hash: {{ .SourceHash }}
+{{ with .Source }}{{ with .BuildConstraint }}
+//go:build {{ . }}
+{{ end }}{{ end }}
+package {{ .PackageName }}
 {{ if gt (.Imports | len) 0 }}
 import ({{ range .Imports }}
 	{{ if .Alias }}{{ .Alias }} {{ end }}"{{ .Path }}"{{ end }}
 )
 {{ end }}
-type {{ .CommandName }} struct {{ print "{" }}{{ range .Fields }}
-	v{{ .Name | Title }}   {{ .Pointer }}{{ .Type }}
-	has{{ .Name | Title }} bool
+` + nestedTemplate + `{{ range .Nested }}
+{{ . }}{{ end }}{{ with .DriftGuard }}
+{{ . }}{{ end }}`
+
+	nestedTemplate = `type {{ .CommandName }}{{ .TypeParams }} struct {{ print "{" }}{{ range .Fields }}
+	v{{ .AccessorName }} {{ .Pointer }}{{ .Type }}
+	has{{ .AccessorName }} bool
 {{ end }}}
 {{range .Constructors }}
 {{ . }}
@@ -24,92 +44,258 @@ type {{ .CommandName }} struct {{ print "{" }}{{ range .Fields }}
 {{ . }}
 {{ end }}`
 
-	constructorTemplate = `func New{{ .Name | Title }}({{ if gt (.Fields | len) 0 }}{{ range .Fields }}
-	v{{ .Name | Title }} {{ .Pointer }}{{ .Type }},{{ end }}
-{{ end }}) {{ if .Mutable }}*{{ end }}{{ .CommandName }} {
-	return {{ if .Mutable }}&{{ end }}{{ .CommandName }}{{ print "{" }}{{ if gt (.Fields | len) 0 }}{{ range .Fields }}
-		v{{ .Name | Title }}: v{{ .Name | Title }},
-		has{{ .Name | Title }}: true,{{ end }}
-	}{{ else }}{{ print "}" }}{{ end }}
+	constructorTemplate = `// New{{ .Name | Title }} creates {{ .CommandName }} command{{ if gt (.Fields | len) 0 }} with {{ range $i, $field := .Fields }}{{ if $i }}, {{ end }}{{ $field.Path }}{{ end }} {{ if eq (.Fields | len) 1 }}field{{ else }}fields{{ end }} set{{ end }}.{{ if .Validate }}
+// It returns the error of the command's Validate method, if any.{{ end }}
+func New{{ .Name | Title }}{{ .TypeParams }}({{ if gt (.Fields | len) 0 }}{{ range .Fields }}
+	v{{ .AccessorName }} {{ if and $.Ptr .Pointer }}{{ slice .Pointer 1 }}{{ else }}{{ .Pointer }}{{ end }}{{ .Type }},{{ end }}
+{{ end }}) {{ if .Validate }}({{ end }}{{ if .Mutable }}*{{ end }}{{ .CommandName }}{{ .TypeArgs }}{{ if .Validate }}, error){{ end }} {
+	{{ if .Validate }}cmd := {{ else }}return {{ end }}{{ if .Mutable }}&{{ end }}{{ .CommandName }}{{ .TypeArgs }}{{ print "{" }}{{ if gt (.Fields | len) 0 }}{{ range .Fields }}
+		v{{ .AccessorName }}: {{ if and $.Ptr .Pointer }}&{{ end }}v{{ .AccessorName }},
+		has{{ .AccessorName }}: true,{{ end }}
+	}{{ else }}{{ print "}" }}{{ end }}{{ if .Validate }}
+
+	if err := cmd.Validate(); err != nil {
+		return {{ if .Mutable }}nil{{ else }}{{ .CommandName }}{{ .TypeArgs }}{{ print "{}" }}{{ end }}, err
+	}
+
+	return cmd, nil{{ end }}
 }`
 
-	getterTemplate = `func (cmd {{ if .Mutable }}*{{ end }}{{ .CommandName }}) {{ .Name | Title }}() {{ .Pointer }}{{ .Type }} {
-	return cmd.v{{ .Name | Title }}
+	driftGuardTemplate = `// {{ .Name }} mirrors fields of {{ .Struct }}, the source struct of {{ .CommandName }}.
+// Converting it to {{ .Struct }} breaks the build when the struct's fields change, regenerate the command then.
+type {{ .Name }}{{ .TypeParams }} struct {{ print "{" }}{{ range .Fields }}
+	{{ . }}{{ end }}
+}
+
+func _{{ .TypeParams }}(v {{ .Name }}{{ .TypeArgs }}) {{ .Struct }} {
+	return {{ .Struct }}(v)
+}`
+
+	getterTemplate = `// {{ .Getter }} returns the value of {{ .Path }} field.{{ with or .Doc .Comment }}
+//
+{{ Comment . }}{{ end }}{{ if and .Deprecated (not (Contains (or .Doc .Comment) .Deprecated)) }}
+//
+{{ Comment .Deprecated }}{{ end }}
+func (cmd {{ if .Mutable }}*{{ end }}{{ .CommandName }}{{ .TypeArgs }}) {{ .Getter }}() {{ .Pointer }}{{ .Type }} {
+	return cmd.v{{ .AccessorName }}
 }`
 
-	setterTemplate = `func (cmd {{ if .Mutable }}*{{ end }}{{ .CommandName }}) Set{{ .Name | Title }}(v {{ .Pointer }}{{ .Type }}) {{ if .Mutable }}*{{ end }}{{ .CommandName }} {
-	cmd.has{{ .Name | Title }} = true
-	cmd.v{{ .Name | Title }} = v
+	setterTemplate = `// {{ .Setter }} {{ if .Mutable }}sets {{ .Path }} field{{ else }}returns a copy of the command with {{ .Path }} field set{{ end }}.{{ with or .Doc .Comment }}
+//
+{{ Comment . }}{{ end }}{{ if and .Deprecated (not (Contains (or .Doc .Comment) .Deprecated)) }}
+//
+{{ Comment .Deprecated }}{{ end }}
+func (cmd {{ if .Mutable }}*{{ end }}{{ .CommandName }}{{ .TypeArgs }}) {{ .Setter }}(v {{ .Pointer }}{{ .Type }}) {{ if .Mutable }}*{{ end }}{{ .CommandName }}{{ .TypeArgs }} {
+	cmd.has{{ .AccessorName }} = true
+	cmd.v{{ .AccessorName }} = v
 
 	return cmd
 }`
 
-	haserTemplate = `func (cmd {{ if .Mutable }}*{{ end }}{{ .CommandName }}) Has{{ .Name | Title }}() bool {
-	return cmd.has{{ .Name | Title }}
+	haserTemplate = `// {{ .Haser }} reports whether {{ .Path }} field is set.{{ with or .Doc .Comment }}
+//
+{{ Comment . }}{{ end }}{{ if and .Deprecated (not (Contains (or .Doc .Comment) .Deprecated)) }}
+//
+{{ Comment .Deprecated }}{{ end }}
+func (cmd {{ if .Mutable }}*{{ end }}{{ .CommandName }}{{ .TypeArgs }}) {{ .Haser }}() bool {
+	return cmd.has{{ .AccessorName }}
 }`
 )
 
 var templateFuncs = template.FuncMap{
-	"Title": func(s string) string {
-		if len(s) == 0 {
-			return ""
+	"Title":    Title,
+	"Comment":  Comment,
+	"Contains": strings.Contains,
+}
+
+// Title upper-cases the first letter of given string.
+func Title(s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// Comment turns text into line comments, e.g. a field's doc comment into the one of a generated method.
+func Comment(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = "//"
+		} else {
+			lines[i] = "// " + line
 		}
+	}
 
-		return strings.ToUpper(s[:1]) + s[1:]
-	},
+	return strings.Join(lines, "\n")
 }
 
-func NewTemplate() (*Template, error) {
-	commandTemplate, err := template.New("command").Funcs(templateFuncs).Parse(commandTemplate)
+type templateOptions struct {
+	dir string
+}
+
+type templateOption func(options *templateOptions)
+
+// TemplateWithDir makes templates from given directory override the default ones.
+// Any of command.tmpl, nested.tmpl, constructor.tmpl, guard.tmpl, getter.tmpl, setter.tmpl and haser.tmpl files
+// replaces the corresponding default template. Additional templates from field/*.tmpl
+// and command/*.tmpl files are executed for every field and once per command respectively.
+func TemplateWithDir(dir string) templateOption {
+	return func(options *templateOptions) {
+		options.dir = dir
+	}
+}
+
+func NewTemplate(options ...templateOption) (*Template, error) {
+	templateOptions := &templateOptions{}
+
+	for _, option := range options {
+		option(templateOptions)
+	}
+
+	commandTemplate, err := parseTemplate(templateOptions.dir, "command", commandTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	nestedTemplate, err := parseTemplate(templateOptions.dir, "nested", nestedTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	constructorTemplate, err := parseTemplate(templateOptions.dir, "constructor", constructorTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	driftGuardTemplate, err := parseTemplate(templateOptions.dir, "guard", driftGuardTemplate)
 	if err != nil {
 		return nil, err
 	}
 
-	constructorTemplate, err := template.New("constructor").Funcs(templateFuncs).Parse(constructorTemplate)
+	getterTemplate, err := parseTemplate(templateOptions.dir, "getter", getterTemplate)
 	if err != nil {
 		return nil, err
 	}
 
-	getterTemplate, err := template.New("getter").Funcs(templateFuncs).Parse(getterTemplate)
+	setterTemplate, err := parseTemplate(templateOptions.dir, "setter", setterTemplate)
 	if err != nil {
 		return nil, err
 	}
 
-	setterTemplate, err := template.New("setter").Funcs(templateFuncs).Parse(setterTemplate)
+	haserTemplate, err := parseTemplate(templateOptions.dir, "haser", haserTemplate)
 	if err != nil {
 		return nil, err
 	}
 
-	haserTemplate, err := template.New("haser").Funcs(templateFuncs).Parse(haserTemplate)
+	fieldTemplates, err := parseExtraTemplates(templateOptions.dir, "field")
+	if err != nil {
+		return nil, err
+	}
+
+	extraCommandTemplates, err := parseExtraTemplates(templateOptions.dir, "command")
 	if err != nil {
 		return nil, err
 	}
 
 	return &Template{
-		commandTemplate:     commandTemplate,
-		constructorTemplate: constructorTemplate,
-		getterTemplate:      getterTemplate,
-		setterTemplate:      setterTemplate,
-		haserTemplate:       haserTemplate,
+		commandTemplate:       commandTemplate,
+		nestedTemplate:        nestedTemplate,
+		constructorTemplate:   constructorTemplate,
+		driftGuardTemplate:    driftGuardTemplate,
+		getterTemplate:        getterTemplate,
+		setterTemplate:        setterTemplate,
+		haserTemplate:         haserTemplate,
+		fieldTemplates:        fieldTemplates,
+		extraCommandTemplates: extraCommandTemplates,
 	}, nil
 }
 
+func parseTemplate(dir, name, defaultText string) (*template.Template, error) {
+	if dir == "" {
+		return template.New(name).Funcs(templateFuncs).Parse(defaultText)
+	}
+
+	path := filepath.Join(dir, name+templateExtension)
+
+	text, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return template.New(name).Funcs(templateFuncs).Parse(defaultText)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tpl, err := template.New(name).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return tpl, nil
+}
+
+func parseExtraTemplates(dir, kind string) ([]*template.Template, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, kind))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []*template.Template
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != templateExtension {
+			continue
+		}
+
+		tpl, err := parseTemplate(filepath.Join(dir, kind), strings.TrimSuffix(entry.Name(), templateExtension), "")
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, tpl)
+	}
+
+	return templates, nil
+}
+
 type Template struct {
 	commandTemplate     *template.Template
+	nestedTemplate      *template.Template
 	constructorTemplate *template.Template
+	driftGuardTemplate  *template.Template
 	getterTemplate      *template.Template
 	setterTemplate      *template.Template
 	haserTemplate       *template.Template
+
+	fieldTemplates        []*template.Template
+	extraCommandTemplates []*template.Template
 }
 
 func (t *Template) ExecuteCommandTemplate(writer io.Writer, data *CommandData) error {
 	return t.commandTemplate.Execute(writer, data)
 }
 
+func (t *Template) ExecuteNestedTemplate(writer io.Writer, data *CommandData) error {
+	return t.nestedTemplate.Execute(writer, data)
+}
+
 func (t *Template) ExecuteConstructorTemplate(writer io.Writer, data *ConstructorData) error {
 	return t.constructorTemplate.Execute(writer, data)
 }
 
+func (t *Template) ExecuteDriftGuardTemplate(writer io.Writer, data *DriftGuardData) error {
+	return t.driftGuardTemplate.Execute(writer, data)
+}
+
 func (t *Template) ExecuteGetterTemplate(writer io.Writer, data *FieldData) error {
 	return t.getterTemplate.Execute(writer, data)
 }
@@ -121,3 +307,34 @@ func (t *Template) ExecuteSetterTemplate(writer io.Writer, data *FieldData) erro
 func (t *Template) ExecuteHaserTemplate(writer io.Writer, data *FieldData) error {
 	return t.haserTemplate.Execute(writer, data)
 }
+
+// ExecuteFieldTemplates executes additional per-field templates and returns their non-blank outputs.
+func (t *Template) ExecuteFieldTemplates(data *FieldData) ([]string, error) {
+	return executeAll(t.fieldTemplates, data)
+}
+
+// ExecuteExtraCommandTemplates executes additional per-command templates and returns their non-blank outputs.
+func (t *Template) ExecuteExtraCommandTemplates(data *CommandData) ([]string, error) {
+	return executeAll(t.extraCommandTemplates, data)
+}
+
+func executeAll(templates []*template.Template, data any) ([]string, error) {
+	var (
+		b       strings.Builder
+		results = make([]string, 0, len(templates))
+	)
+
+	for _, tpl := range templates {
+		if err := tpl.Execute(&b, data); err != nil {
+			return nil, err
+		}
+
+		if strings.TrimSpace(b.String()) != "" {
+			results = append(results, b.String())
+		}
+
+		b.Reset()
+	}
+
+	return results, nil
+}
@@ -0,0 +1,58 @@
+package types
+
+import "go/types"
+
+// Kind classifies a field's type, so templates can generate different code for different types.
+type Kind struct {
+	Pointer    bool
+	Slice      bool
+	Array      bool
+	Map        bool
+	Chan       bool
+	Named      bool
+	Interface  bool
+	Func       bool
+	Struct     bool
+	Basic      bool
+	TypeParam  bool
+	Comparable bool
+}
+
+func KindOf(t types.Type) Kind {
+	kind := Kind{
+		Comparable: types.Comparable(t),
+	}
+
+	switch t.(type) {
+	case *types.Named:
+		kind.Named = true
+
+	case *types.TypeParam:
+		kind.TypeParam = true
+
+		return kind
+	}
+
+	switch t.Underlying().(type) {
+	case *types.Pointer:
+		kind.Pointer = true
+	case *types.Slice:
+		kind.Slice = true
+	case *types.Array:
+		kind.Array = true
+	case *types.Map:
+		kind.Map = true
+	case *types.Chan:
+		kind.Chan = true
+	case *types.Interface:
+		kind.Interface = true
+	case *types.Signature:
+		kind.Func = true
+	case *types.Struct:
+		kind.Struct = true
+	case *types.Basic:
+		kind.Basic = true
+	}
+
+	return kind
+}
@@ -2,21 +2,42 @@ package types
 
 import (
 	"fmt"
+	"go/token"
 	"go/types"
+	"regexp"
 	"strings"
 
 	"github.com/donatorsky/go-cmder/internal/utils"
 	"golang.org/x/tools/go/packages"
 )
 
-func NewRegistry(pkg *packages.Package) *Registry {
+var (
+	majorVersionPattern  = regexp.MustCompile(`^v[0-9]+$`)
+	nonIdentifierPattern = regexp.MustCompile(`[^[:alnum:]_]`)
+)
+
+func NewRegistry(targetPkg, sourcePkg *packages.Package) *Registry {
 	r := Registry{
-		selfPkg: pkg.PkgPath,
-		types:   map[string]*Type{},
-		imports: utils.NewUniqueSlice[*Type](),
+		selfPkg:        targetPkg.PkgPath,
+		types:          map[string]*Type{},
+		preferredNames: map[string]string{},
+		names:          map[string]string{},
+		packages:       map[string]*types.Package{},
+		imports:        utils.NewUniqueSlice[*Type](),
+	}
+
+	r.Reserve(types.Universe.Names()...)
+	r.Reserve(targetPkg.Name)
+
+	if sourcePkg.PkgPath != targetPkg.PkgPath {
+		r.types[sourcePkg.PkgPath] = &Type{
+			Alias: nil,
+			Name:  sourcePkg.Name,
+			Path:  sourcePkg.PkgPath,
+		}
 	}
 
-	for _, p := range pkg.Imports {
+	for _, p := range sourcePkg.Imports {
 		r.types[p.PkgPath] = &Type{
 			Alias: nil,
 			Name:  p.Name,
@@ -24,18 +45,19 @@ func NewRegistry(pkg *packages.Package) *Registry {
 		}
 	}
 
-	for _, syntax := range pkg.Syntax {
+	for _, syntax := range sourcePkg.Syntax {
 		for _, importSpec := range syntax.Imports {
 			if importSpec.Name == nil || importSpec.Name.Name == "." || importSpec.Name.Name == "_" {
 				continue
 			}
 
-			t, ok := r.types[strings.Trim(importSpec.Path.Value, `"`)]
-			if !ok {
+			importPath := strings.Trim(importSpec.Path.Value, `"`)
+
+			if _, ok := r.preferredNames[importPath]; ok {
 				continue
 			}
 
-			t.Alias = &importSpec.Name.Name
+			r.preferredNames[importPath] = importSpec.Name.Name
 		}
 	}
 
@@ -43,15 +65,34 @@ func NewRegistry(pkg *packages.Package) *Registry {
 }
 
 type Registry struct {
-	selfPkg string
-	types   map[string]*Type
-	imports *utils.UniqueSlice[*Type]
+	selfPkg        string
+	types          map[string]*Type
+	preferredNames map[string]string
+	names          map[string]string
+	packages       map[string]*types.Package
+	imports        *utils.UniqueSlice[*Type]
+}
+
+// Reserve marks given identifiers as used in the generated file, so no import is named after them.
+func (r *Registry) Reserve(names ...string) {
+	for _, name := range names {
+		if _, ok := r.names[name]; !ok {
+			r.names[name] = ""
+		}
+	}
 }
 
 func (r *Registry) Imports() []*Type {
 	return r.imports.Items()
 }
 
+// Package returns the package of given path imported by resolved types, or nil.
+func (r *Registry) Package(path string) *types.Package {
+	return r.packages[path]
+}
+
+// Resolve renders given type as it should be written in the target package, recording
+// imports of all the packages it refers to. Pointer prefix is returned separately.
 func (r *Registry) Resolve(fieldType types.Type) (pointer string, unwrappedType string, _ error) {
 	for {
 		pointerType, ok := fieldType.(*types.Pointer)
@@ -63,46 +104,272 @@ func (r *Registry) Resolve(fieldType types.Type) (pointer string, unwrappedType
 		fieldType = pointerType.Elem()
 	}
 
-	switch actualType := fieldType.(type) {
-	case *types.Named,
-		*types.Struct,
-		*types.Signature:
-		typeFQN := actualType.String()
+	if err := r.checkAccessible(fieldType); err != nil {
+		return "", "", err
+	}
 
-		for name, t := range r.types {
-			if !strings.Contains(typeFQN, fmt.Sprintf("%s.", name)) {
-				continue
+	return pointer, types.TypeString(fieldType, r.qualifier), nil
+}
+
+// ResolveTypeParams renders given type parameters as a type parameter list, e.g. "[K comparable, V any]",
+// and as a list of type arguments referring to them, e.g. "[K, V]".
+func (r *Registry) ResolveTypeParams(typeParams *types.TypeParamList) (params string, args string, _ error) {
+	if typeParams.Len() == 0 {
+		return "", "", nil
+	}
+
+	paramsList := make([]string, 0, typeParams.Len())
+	argsList := make([]string, 0, typeParams.Len())
+
+	for i := 0; i < typeParams.Len(); i++ {
+		typeParam := typeParams.At(i)
+
+		if err := r.checkAccessible(typeParam.Constraint()); err != nil {
+			return "", "", err
+		}
+
+		name := typeParam.Obj().Name()
+
+		paramsList = append(paramsList, fmt.Sprintf("%s %s", name, types.TypeString(typeParam.Constraint(), r.qualifier)))
+		argsList = append(argsList, name)
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(paramsList, ", ")), fmt.Sprintf("[%s]", strings.Join(argsList, ", ")), nil
+}
+
+// qualifier returns the name under which given package is imported in the target package.
+func (r *Registry) qualifier(pkg *types.Package) string {
+	if pkg.Path() == r.selfPkg {
+		return ""
+	}
+
+	r.packages[pkg.Path()] = pkg
+
+	t, ok := r.types[pkg.Path()]
+	if !ok {
+		t = &Type{
+			Alias: nil,
+			Name:  pkg.Name(),
+			Path:  pkg.Path(),
+		}
+
+		r.types[pkg.Path()] = t
+	}
+
+	if !r.imports.Has(t) {
+		if name := r.allocateName(t); name != t.Name {
+			t.Alias = &name
+		}
+
+		_, _ = r.imports.Append(t)
+	}
+
+	if t.Alias != nil {
+		return *t.Alias
+	}
+
+	return t.Name
+}
+
+// allocateName picks a unique identifier to import given package under. Candidates are tried in order:
+// the alias chosen in the source package, the package name, the package name prefixed with
+// its parent path element and the package name with a numeric suffix.
+func (r *Registry) allocateName(t *Type) string {
+	var candidates []string
+
+	if preferredName, ok := r.preferredNames[t.Path]; ok {
+		candidates = append(candidates, preferredName)
+	}
+
+	candidates = append(candidates, t.Name)
+
+	if parent := parentPathElement(t); parent != "" {
+		candidates = append(candidates, parent+t.Name)
+	}
+
+	for _, candidate := range candidates {
+		if r.isFree(candidate) {
+			r.names[candidate] = t.Path
+
+			return candidate
+		}
+	}
+
+	for i := 2; ; i++ {
+		if candidate := fmt.Sprintf("%s%d", t.Name, i); r.isFree(candidate) {
+			r.names[candidate] = t.Path
+
+			return candidate
+		}
+	}
+}
+
+func (r *Registry) isFree(name string) bool {
+	_, used := r.names[name]
+
+	return !used && token.IsIdentifier(name)
+}
+
+// parentPathElement returns the package's import path element preceding its name,
+// skipping version suffixes, e.g. "gofrs" for github.com/gofrs/uuid/v5.
+func parentPathElement(t *Type) string {
+	elements := strings.Split(t.Path, "/")
+
+	for i := len(elements) - 1; i >= 0; i-- {
+		element := elements[i]
+
+		if majorVersionPattern.MatchString(element) || strings.Contains(element, t.Name) {
+			continue
+		}
+
+		element = strings.ToLower(nonIdentifierPattern.ReplaceAllString(element, ""))
+		if !token.IsIdentifier(element) {
+			return ""
+		}
+
+		return element
+	}
+
+	return ""
+}
+
+// CheckAccessible reports an error when given type cannot be written in the target package,
+// e.g. it is unexported in another package. Nothing is recorded.
+func (r *Registry) CheckAccessible(t types.Type) error {
+	return r.checkAccessible(t)
+}
+
+// checkAccessible walks given type and reports types, fields and methods that cannot be
+// referred to from the target package.
+func (r *Registry) checkAccessible(t types.Type) error {
+	switch actualType := t.(type) {
+	case *types.Basic, *types.TypeParam, nil:
+		return nil
+
+	case *types.Named:
+		obj := actualType.Obj()
+
+		if obj.Pkg() != nil && obj.Pkg().Path() != r.selfPkg && !obj.Exported() {
+			return fmt.Errorf("type %s is unexported in package %s", obj.Name(), obj.Pkg().Path())
+		}
+
+		if typeArgs := actualType.TypeArgs(); typeArgs != nil {
+			for i := 0; i < typeArgs.Len(); i++ {
+				if err := r.checkAccessible(typeArgs.At(i)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+
+	case *types.Pointer:
+		return r.checkAccessible(actualType.Elem())
+
+	case *types.Slice:
+		return r.checkAccessible(actualType.Elem())
+
+	case *types.Array:
+		return r.checkAccessible(actualType.Elem())
+
+	case *types.Chan:
+		return r.checkAccessible(actualType.Elem())
+
+	case *types.Map:
+		if err := r.checkAccessible(actualType.Key()); err != nil {
+			return err
+		}
+
+		return r.checkAccessible(actualType.Elem())
+
+	case *types.Struct:
+		for i := 0; i < actualType.NumFields(); i++ {
+			field := actualType.Field(i)
+
+			if err := r.checkObjectAccessible(field, "field"); err != nil {
+				return err
 			}
 
-			alias := t.Name
-			if t.Alias != nil {
-				alias = *t.Alias
+			if err := r.checkAccessible(field.Type()); err != nil {
+				return err
 			}
+		}
 
-			typeFQN = strings.ReplaceAll(typeFQN, fmt.Sprintf("%s.", name), fmt.Sprintf("%s.", alias))
+		return nil
 
-			_, _ = r.imports.Append(t)
+	case *types.Signature:
+		if err := r.checkAccessible(actualType.Params()); err != nil {
+			return err
 		}
 
-		return pointer, strings.ReplaceAll(typeFQN, fmt.Sprintf("%s.", r.selfPkg), ""), nil
+		return r.checkAccessible(actualType.Results())
 
-	case *types.Slice:
-		elemPointer, elemType, err := r.Resolve(actualType.Elem())
-		if err != nil {
-			return "", "", err
+	case *types.Tuple:
+		for i := 0; i < actualType.Len(); i++ {
+			if err := r.checkAccessible(actualType.At(i).Type()); err != nil {
+				return err
+			}
 		}
 
-		return pointer, fmt.Sprintf("[]%s%s", elemPointer, elemType), nil
+		return nil
 
-	case *types.Array:
-		elemPointer, elemType, err := r.Resolve(actualType.Elem())
-		if err != nil {
-			return "", "", err
+	case *types.Interface:
+		for i := 0; i < actualType.NumExplicitMethods(); i++ {
+			method := actualType.ExplicitMethod(i)
+
+			if err := r.checkObjectAccessible(method, "method"); err != nil {
+				return err
+			}
+
+			if err := r.checkAccessible(method.Type()); err != nil {
+				return err
+			}
+		}
+
+		for i := 0; i < actualType.NumEmbeddeds(); i++ {
+			if err := r.checkAccessible(actualType.EmbeddedType(i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case *types.Union:
+		for i := 0; i < actualType.Len(); i++ {
+			if err := r.checkAccessible(actualType.Term(i).Type()); err != nil {
+				return err
+			}
 		}
 
-		return pointer, fmt.Sprintf("[%d]%s%s", actualType.Len(), elemPointer, elemType), nil
+		return nil
+
+	case alias:
+		obj := actualType.Obj()
+
+		if obj.Pkg() != nil && obj.Pkg().Path() != r.selfPkg && !obj.Exported() {
+			return fmt.Errorf("type %s is unexported in package %s", obj.Name(), obj.Pkg().Path())
+		}
+
+		return r.checkAccessible(actualType.Rhs())
 
 	default:
-		return pointer, fieldType.String(), nil
+		return fmt.Errorf("unsupported type %s", t)
 	}
 }
+
+// alias matches types.Alias, available only in newer Go versions.
+type alias interface {
+	types.Type
+	Obj() *types.TypeName
+	Rhs() types.Type
+}
+
+// checkObjectAccessible reports unexported fields and methods of type literals declared in other packages,
+// as such literals are not identical to the ones written in the target package.
+func (r *Registry) checkObjectAccessible(obj types.Object, kind string) error {
+	if obj.Exported() || obj.Pkg() == nil || obj.Pkg().Path() == r.selfPkg {
+		return nil
+	}
+
+	return fmt.Errorf("%s %s is unexported in package %s", kind, obj.Name(), obj.Pkg().Path())
+}
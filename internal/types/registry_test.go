@@ -0,0 +1,144 @@
+package types
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestRegistryAliases(t *testing.T) {
+	named := func(path, pkgName, name string) types.Type {
+		pkg := types.NewPackage(path, pkgName)
+
+		return types.NewNamed(types.NewTypeName(token.NoPos, pkg, name, nil), types.NewStruct(nil, nil), nil)
+	}
+
+	aError := named("github.com/a/errors", "errors", "Error")
+	bError := named("github.com/b/errors", "errors", "Error")
+	cError := named("github.com/c/errors", "errors", "Error")
+	v5UUID := named("github.com/gofrs/uuid/v5", "uuid", "UUID")
+
+	file, err := parser.ParseFile(token.NewFileSet(), "source.go", `package app
+
+import (
+	cerr "github.com/c/errors"
+	"github.com/a/errors"
+)
+`, parser.ImportsOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		reserved []string
+		types    []types.Type
+		expected []string
+		imports  map[string]string
+	}{
+		{
+			name:     "packages named the same",
+			types:    []types.Type{aError, bError, types.NewPointer(aError)},
+			expected: []string{"errors.Error", "berrors.Error", "*errors.Error"},
+			imports: map[string]string{
+				"github.com/a/errors": "",
+				"github.com/b/errors": "berrors",
+			},
+		},
+		{
+			name:     "packages named the same in reverse order",
+			types:    []types.Type{bError, aError},
+			expected: []string{"errors.Error", "aerrors.Error"},
+			imports: map[string]string{
+				"github.com/a/errors": "aerrors",
+				"github.com/b/errors": "",
+			},
+		},
+		{
+			name:     "package name clashing with a local identifier",
+			reserved: []string{"errors", "berrors"},
+			types:    []types.Type{aError, bError},
+			expected: []string{"aerrors.Error", "errors2.Error"},
+			imports: map[string]string{
+				"github.com/a/errors": "aerrors",
+				"github.com/b/errors": "errors2",
+			},
+		},
+		{
+			name:     "alias chosen in the source package",
+			types:    []types.Type{cError, aError},
+			expected: []string{"cerr.Error", "errors.Error"},
+			imports: map[string]string{
+				"github.com/a/errors": "",
+				"github.com/c/errors": "cerr",
+			},
+		},
+		{
+			name:     "version suffix is skipped in aliases",
+			reserved: []string{"uuid"},
+			types:    []types.Type{types.NewSlice(v5UUID)},
+			expected: []string{"[]gofrsuuid.UUID"},
+			imports: map[string]string{
+				"github.com/gofrs/uuid/v5": "gofrsuuid",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Registries are built from maps, e.g. of the source package's imports, so aliases
+			// are allocated repeatedly to catch any dependence on the maps' iteration order.
+			for i := 0; i < 20; i++ {
+				sourcePkg := &packages.Package{
+					PkgPath: "example.com/app",
+					Name:    "app",
+					Imports: map[string]*packages.Package{},
+					Syntax:  []*ast.File{file},
+				}
+
+				for _, path := range []string{"github.com/a/errors", "github.com/b/errors", "github.com/c/errors", "github.com/gofrs/uuid/v5"} {
+					sourcePkg.Imports[path] = &packages.Package{PkgPath: path, Name: "errors"}
+				}
+
+				sourcePkg.Imports["github.com/gofrs/uuid/v5"].Name = "uuid"
+
+				r := NewRegistry(sourcePkg, sourcePkg)
+				r.Reserve(tt.reserved...)
+
+				actual := make([]string, 0, len(tt.types))
+
+				for _, fieldType := range tt.types {
+					pointer, typeName, err := r.Resolve(fieldType)
+					if err != nil {
+						t.Fatalf("Resolve(%s) unexpected error: %v", fieldType, err)
+					}
+
+					actual = append(actual, pointer+typeName)
+				}
+
+				if !reflect.DeepEqual(actual, tt.expected) {
+					t.Fatalf("Resolve() = %v, expected %v", actual, tt.expected)
+				}
+
+				imports := map[string]string{}
+
+				for _, imported := range r.Imports() {
+					imports[imported.Path] = ""
+
+					if imported.Alias != nil {
+						imports[imported.Path] = *imported.Alias
+					}
+				}
+
+				if !reflect.DeepEqual(imports, tt.imports) {
+					t.Fatalf("Imports() = %v, expected %v", imports, tt.imports)
+				}
+			}
+		})
+	}
+}
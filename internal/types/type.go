@@ -1,7 +1,5 @@
 package types
 
-import "fmt"
-
 type Type struct {
 	Alias *string
 	Name  string
@@ -9,5 +7,5 @@ type Type struct {
 }
 
 func (t *Type) UniqueValue() any {
-	return fmt.Sprintf("%#v", t)
+	return t.Path
 }
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOp byte
+
+const (
+	diffEqual  diffOp = ' '
+	diffDelete diffOp = '-'
+	diffInsert diffOp = '+'
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// UnifiedDiff returns the unified diff of given texts, or an empty string when they are equal.
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(lines); {
+		if lines[start].op == diffEqual {
+			start++
+
+			continue
+		}
+
+		// Extend the hunk while changes are separated by no more than twice the context.
+		end := start
+		for i := start; i < len(lines) && i-end <= 2*diffContextLines; i++ {
+			if lines[i].op != diffEqual {
+				end = i + 1
+			}
+		}
+
+		from := start - diffContextLines
+		if from < 0 {
+			from = 0
+		}
+
+		to := end + diffContextLines
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		writeHunk(&b, lines, from, to)
+
+		start = to
+	}
+
+	return b.String()
+}
+
+func writeHunk(b *strings.Builder, lines []diffLine, from, to int) {
+	var oldStart, newStart, oldCount, newCount int
+
+	for _, line := range lines[:from] {
+		if line.op != diffInsert {
+			oldStart++
+		}
+
+		if line.op != diffDelete {
+			newStart++
+		}
+	}
+
+	for _, line := range lines[from:to] {
+		if line.op != diffInsert {
+			oldCount++
+		}
+
+		if line.op != diffDelete {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+
+	for _, line := range lines[from:to] {
+		b.WriteByte(byte(line.op))
+		b.WriteString(line.text)
+
+		if !strings.HasSuffix(line.text, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// diffLines computes an edit script turning old lines into new ones, based on their longest common subsequence.
+func diffLines(oldLines, newLines []string) []diffLine {
+	var prefix, suffix []diffLine
+
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[0] == newLines[0] {
+		prefix = append(prefix, diffLine{op: diffEqual, text: oldLines[0]})
+		oldLines, newLines = oldLines[1:], newLines[1:]
+	}
+
+	for len(oldLines) > 0 && len(newLines) > 0 && oldLines[len(oldLines)-1] == newLines[len(newLines)-1] {
+		suffix = append([]diffLine{{op: diffEqual, text: oldLines[len(oldLines)-1]}}, suffix...)
+		oldLines, newLines = oldLines[:len(oldLines)-1], newLines[:len(newLines)-1]
+	}
+
+	lcs := make([][]int32, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] > lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := prefix
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			lines = append(lines, diffLine{op: diffEqual, text: oldLines[i]})
+			i++
+			j++
+
+		// Deletions come first when both keep the common subsequence, like in diff and git.
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{op: diffDelete, text: oldLines[i]})
+			i++
+
+		default:
+			lines = append(lines, diffLine{op: diffInsert, text: newLines[j]})
+			j++
+		}
+	}
+
+	return append(lines, suffix...)
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	numbers := func(replacements map[int]string) string {
+		var b strings.Builder
+
+		for i := 1; i <= 20; i++ {
+			if replacement, ok := replacements[i]; ok {
+				fmt.Fprintf(&b, "%s\n", replacement)
+			} else {
+				fmt.Fprintf(&b, "%d\n", i)
+			}
+		}
+
+		return b.String()
+	}
+
+	tests := []struct {
+		name     string
+		oldText  string
+		newText  string
+		expected string
+	}{
+		{
+			name:     "equal texts",
+			oldText:  "a\nb\n",
+			newText:  "a\nb\n",
+			expected: "",
+		},
+		{
+			name:    "replaced line deletes before inserting",
+			oldText: "a\nb\nc\n",
+			newText: "a\nB\nc\n",
+			expected: `--- old
++++ new
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			name:    "changes separated by up to twice the context are merged",
+			oldText: numbers(nil),
+			newText: numbers(map[int]string{2: "two", 9: "nine"}),
+			expected: `--- old
++++ new
+@@ -1,12 +1,12 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
++nine
+ 10
+ 11
+ 12
+`,
+		},
+		{
+			name:    "distant changes make separate hunks",
+			oldText: numbers(nil),
+			newText: numbers(map[int]string{2: "two", 10: "ten"}),
+			expected: `--- old
++++ new
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -7,7 +7,7 @@
+ 7
+ 8
+ 9
+-10
++ten
+ 11
+ 12
+ 13
+`,
+		},
+		{
+			name:    "missing trailing newline",
+			oldText: "a\nb",
+			newText: "a\nc",
+			expected: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+		{
+			name:    "added trailing newline",
+			oldText: "a",
+			newText: "a\n",
+			expected: `--- old
++++ new
+@@ -1 +1 @@
+-a
+\ No newline at end of file
++a
+`,
+		},
+		{
+			name:    "empty old file",
+			oldText: "",
+			newText: "x\ny\n",
+			expected: `--- old
++++ new
+@@ -0,0 +1,2 @@
++x
++y
+`,
+		},
+		{
+			name:    "empty new file",
+			oldText: "x\ny\n",
+			newText: "",
+			expected: `--- old
++++ new
+@@ -1,2 +0,0 @@
+-x
+-y
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := UnifiedDiff("old", "new", tt.oldText, tt.newText); actual != tt.expected {
+				t.Errorf("UnifiedDiff() =\n%s\nexpected:\n%s", actual, tt.expected)
+			}
+		})
+	}
+}
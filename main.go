@@ -2,266 +2,369 @@ package main
 
 import (
 	"bytes"
-	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"go/types"
-	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 
-	"github.com/donatorsky/go-cmder/internal/template"
-	internalTypes "github.com/donatorsky/go-cmder/internal/types"
+	"github.com/donatorsky/go-cmder/cmder"
 	"github.com/donatorsky/go-cmder/internal/utils"
-	"golang.org/x/tools/go/packages"
 )
 
-var filenamePattern = regexp.MustCompile(`(ID|JSON|URL|[[:upper:]])`)
-
 func main() {
-	logger := slog.NewLogLogger(slog.NewTextHandler(os.Stdout, nil), slog.LevelError)
-
-	params := newParams()
-
-	flag.BoolVar(&params.mutable, "mutable", false, "Whether the generated command should be mutable.")
-	flag.BoolVar(&params.includeUnexported, "include-unexported", false, "Whether to include unexported fields.")
-	flag.BoolVar(&params.sorted, "sorted", false, "Whether to generate fields in alphabetic ascending order.")
-	flag.StringVar(&params.out, "out", "", "Where write to the generated command.")
-	flag.Var(params.exclude, "exclude", "Struct field's name to ignore when generating command.")
-	flag.Var(params.include, "include", "Struct field's name to generate command from. Overrides -exclude flag.")
-	flag.Var(params.constructor, "constructor", `Constructor name and comma-separated list of fields.
-Use "default" as a constructor name to generate default constructor.
+	cmd, args := subcommands[0], os.Args[1:]
 
-E.g.:
--constructor default:foo,bar CreateStructCmd       // Generates NewCreateStructCmd(foo fooType, bar barType)
--constructor WithFooAndBar:foo,bar CreateStructCmd // Generates NewCreateStructCmdWithFooAndBar(foo fooType, bar barType)`)
-
-	flag.Usage = func() {
-		fmt.Println(`go-cmder [flags] struct CommandName`)
-		flag.PrintDefaults()
-	}
-
-	flag.Parse()
-
-	if flag.NArg() < 2 {
-		logger.Fatalln("Missing required arguments")
+	// Without a subcommand, arguments are passed to generate for backward compatibility.
+	if len(args) > 0 {
+		if found := findSubcommand(args[0]); found != nil {
+			cmd, args = found, args[1:]
+		}
 	}
 
-	params.structName = flag.Arg(0)
-	params.commandName = flag.Arg(1)
+	// Flags' errors and usage are buffered, so they are printed as a diagnostic with -json flag.
+	var usage bytes.Buffer
 
-	if params.out == "" {
-		params.out = fmt.Sprintf(
-			"%s.go",
-			strings.ToLower(strings.Trim(filenamePattern.ReplaceAllString(params.commandName, "_$1"), "_")),
-		)
-	}
+	flags := flag.NewFlagSet("go-cmder "+cmd.name, flag.ContinueOnError)
+	flags.SetOutput(&usage)
+	jsonOutput := flags.Bool("json", false, "Whether to print diagnostics as a JSON array to the standard error instead of file:line:col: message lines.")
 
-	cwd, err := os.Getwd()
-	if err != nil {
-		logger.Fatalf("Could not get current working directory: %s\n", err)
-	}
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), cmd.usage)
 
-	params.out = filepath.Join(cwd, params.out)
+		if cmd == subcommands[0] {
+			fmt.Fprintln(flags.Output(), "\nSubcommands:")
 
-	pkgs, err := packages.Load(&packages.Config{
-		Mode: packages.NeedName | packages.NeedSyntax | packages.NeedTypes | packages.NeedImports,
-		Dir:  cwd,
-	})
-	if err != nil {
-		logger.Fatalf("Could not load source package: %s\n", err)
-	}
-	if len(pkgs) == 0 {
-		logger.Fatalln("package not found")
-	}
-	if len(pkgs) > 1 {
-		logger.Fatalln("found more than one package")
-	}
-	if len(pkgs[0].Errors) > 0 {
-		logger.Fatalf("failures: %s\n", pkgs[0].Errors)
-	}
-
-	typesRegistry := internalTypes.NewRegistry(pkgs[0])
+			for _, subcommand := range subcommands {
+				fmt.Fprintf(flags.Output(), "  %-8s %s\n", subcommand.name, subcommand.description)
+			}
+		}
 
-	obj := pkgs[0].Types.Scope().Lookup(params.structName)
-	if obj == nil {
-		logger.Fatalf("struct %s not found\n", params.structName)
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		flags.PrintDefaults()
 	}
 
-	structType, ok := obj.Type().Underlying().(*types.Struct)
-	if !ok {
-		logger.Fatalf("%s (%s) is not a struct", params.structName, obj.Type())
-	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	fields := utils.NewUniqueSlice[*template.FieldData](
-		utils.UniqueSliceWithCapacity(uint(structType.NumFields() - params.exclude.Len())),
-	)
+	err := cmd.run(ctx, flags, args)
 
-	for i := 0; i < structType.NumFields(); i++ {
-		field := structType.Field(i)
+	var flagsErr *flagsError
+	if errors.As(err, &flagsErr) && (errors.Is(err, flag.ErrHelp) || !*jsonOutput && !jsonRequested(args)) {
+		_, _ = os.Stderr.Write(usage.Bytes())
 
-		if params.include.Empty() {
-			if params.exclude.Has(field.Name()) {
-				continue
-			}
-		} else {
-			if !params.include.Has(field.Name()) {
-				continue
-			}
+		if errors.Is(err, flag.ErrHelp) {
+			return
 		}
 
-		if !params.includeUnexported && !field.Exported() {
-			continue
-		}
+		stop()
+		os.Exit(2)
+	}
 
-		commandDataField := &template.FieldData{
-			CommandName: params.commandName,
-			Mutable:     params.mutable,
-			Name:        field.Name(),
-		}
+	diagnostics := cmder.Diagnostics(err)
 
-		commandDataField.Pointer, commandDataField.Type, err = typesRegistry.Resolve(field.Type())
-		if err != nil {
-			logger.Fatalf(err.Error())
+	if *jsonOutput || flagsErr != nil {
+		if diagnostics == nil {
+			diagnostics = []*cmder.Diagnostic{}
 		}
 
-		if fields.Has(commandDataField) {
-			logger.Fatalf("Fields' names conflict with %q.", commandDataField.Name)
+		if err := json.NewEncoder(os.Stderr).Encode(diagnostics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	} else {
+		for _, d := range diagnostics {
+			fmt.Print(d.Diff)
+			fmt.Fprintln(os.Stderr, d.String())
 		}
-
-		_, _ = fields.Append(commandDataField)
-	}
-
-	if params.sorted {
-		fields.Sort(func(i, j *template.FieldData) int {
-			return cmp.Compare(i.Name, j.Name)
-		})
 	}
 
-	tpl, err := template.NewTemplate()
-	if err != nil {
-		logger.Fatalf("Failed to parse command template: %s\n", err)
+	if cmder.HasErrors(diagnostics) {
+		stop()
+		os.Exit(1)
 	}
+}
 
-	var b bytes.Buffer
+// flagsError reports invalid flags of a subcommand.
+type flagsError struct {
+	err error
+}
 
-	var constructors []string
-	for _, constructor := range params.constructor.Items() {
-		constructorData := template.ConstructorData{
-			CommandName: params.commandName,
-			Mutable:     params.mutable,
-			Name:        params.commandName,
-			Fields:      make([]*template.FieldData, 0, len(constructor.Params)),
-		}
+func (e *flagsError) Error() string {
+	return e.err.Error()
+}
 
-		if strings.ToLower(constructor.Name) != "default" {
-			constructorData.Name = fmt.Sprintf("%s%s", params.commandName, constructor.Name)
-		}
+func (e *flagsError) Unwrap() error {
+	return e.err
+}
 
-		for _, param := range constructor.Params {
-			fieldData := &template.FieldData{
-				Name: param,
-			}
+// parseFlags parses the subcommand's flags, reporting their errors with flagsError.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return &flagsError{err: err}
+	}
 
-			if err := fields.GetByItem(&fieldData); err != nil {
-				logger.Fatalf("Cannot build %s constructor: field %s does not exist, is excluded or not included", constructor.Name, param)
-			}
+	return nil
+}
 
-			constructorData.Fields = append(constructorData.Fields, fieldData)
+// jsonRequested reports whether -json flag is given among arguments, also after an invalid flag
+// that stopped parsing them.
+func jsonRequested(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
 		}
 
-		if err := tpl.ExecuteConstructorTemplate(&b, &constructorData); err != nil {
-			logger.Fatalf("Failed to generate command getter: %s\n", err)
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if name != "json" {
+			continue
 		}
 
-		constructors = append(constructors, b.String())
-		b.Reset()
-	}
+		enabled, err := strconv.ParseBool(value)
 
-	var methods []string
-
-	for _, field := range fields.Items() {
-		if err := tpl.ExecuteGetterTemplate(&b, field); err != nil {
-			logger.Fatalf("Failed to generate command getter: %s\n", err)
-		}
+		return !hasValue || err == nil && enabled
+	}
 
-		methods = append(methods, b.String())
-		b.Reset()
+	return false
+}
 
-		if err := tpl.ExecuteSetterTemplate(&b, field); err != nil {
-			logger.Fatalf("Failed to generate command setter: %s\n", err)
+// isPackagePatterns reports whether arguments are package patterns, e.g. "./...", "." or "./app",
+// rather than struct and command names. Every argument has to look like a package pattern,
+// so a lone struct name, e.g. with the command name forgotten, is not taken for a package.
+func isPackagePatterns(args []string) bool {
+	for _, arg := range args {
+		if !isPackagePattern(arg) {
+			return false
 		}
+	}
 
-		methods = append(methods, b.String())
-		b.Reset()
-
-		if err := tpl.ExecuteHaserTemplate(&b, field); err != nil {
-			logger.Fatalf("Failed to generate command haser: %s\n", err)
-		}
+	return len(args) > 0
+}
 
-		methods = append(methods, b.String())
-		b.Reset()
+// isPackagePattern reports whether the argument is a relative or absolute package path, a pattern with "...",
+// or an import path whose first element has a dot, e.g. example.com/app.
+func isPackagePattern(arg string) bool {
+	if arg == "." || arg == ".." || strings.Contains(arg, "...") ||
+		strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") || strings.HasPrefix(arg, "/") {
+		return true
 	}
 
-	file, err := os.OpenFile(params.out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
-		logger.Fatalf("Failed to open command output file for writing: %s\n", err)
-	}
+	domain, _, ok := strings.Cut(arg, "/")
 
-	if err := tpl.ExecuteCommandTemplate(file, &template.CommandData{
-		PackageName:  pkgs[0].Name,
-		Imports:      typesRegistry.Imports(),
-		CommandName:  params.commandName,
-		Fields:       fields.Items(),
-		Constructors: constructors,
-		Methods:      methods,
-	}); err != nil {
-		logger.Fatalf("Failed to write command output file: %s\n", err)
-	}
+	return ok && strings.Contains(domain, ".")
 }
 
-func newParams() params {
-	return params{
+func newParams(flags *flag.FlagSet) *params {
+	p := &params{
 		exclude: utils.NewUniqueMultiFlag(utils.StringSetter),
 		include: utils.NewUniqueMultiFlag(utils.StringSetter),
-		constructor: utils.NewUniqueMultiFlag(
-			func(value string) (c constructor, _ error) {
-				nameAndParams := strings.SplitN(value, ":", 2)
+		embedded: utils.NewUniqueMultiFlag(
+			func(value string) (e embeddedField, err error) {
+				e.name, e.mode, err = cmder.ParseEmbedded(value)
 
-				c.Name = nameAndParams[0]
+				return
+			},
+			utils.UniqueSliceWithOnDuplicateKeyError(func(key, item any) error {
+				return fmt.Errorf("duplicated embedded field %q", key)
+			}),
+		),
+		nested: utils.NewUniqueMultiFlag(
+			func(value string) (n nestedCommand, err error) {
+				n.path, n.name, err = cmder.ParseNested(value)
 
-				if len(nameAndParams) == 2 {
-					c.Params = strings.Split(nameAndParams[1], ",")
-				}
+				return
+			},
+			utils.UniqueSliceWithOnDuplicateKeyError(func(key, item any) error {
+				return fmt.Errorf("duplicated nested command field %q", key)
+			}),
+		),
+		rename: utils.NewUniqueMultiFlag(
+			func(value string) (r renamedField, err error) {
+				r.path, r.name, err = cmder.ParseRename(value)
 
 				return
 			},
+			utils.UniqueSliceWithOnDuplicateKeyError(func(key, item any) error {
+				return fmt.Errorf("duplicated field rename %q", key)
+			}),
+		),
+		constructor: utils.NewUniqueMultiFlag(
+			cmder.ParseConstructor,
 			utils.UniqueSliceWithOnDuplicateKeyError(func(key, item any) error {
 				return fmt.Errorf("duplicated constructor name %q", key)
 			}),
 		),
 	}
+
+	flags.BoolVar(&p.mutable, "mutable", false, "Whether the generated command should be mutable.")
+	flags.BoolVar(&p.includeUnexported, "include-unexported", false, "Whether to include unexported fields.")
+	flags.BoolVar(&p.sorted, "sorted", false, "Whether to generate fields in alphabetic ascending order.")
+	flags.BoolVar(&p.initialisms, "initialisms", false, "Whether to upper-case initialisms in accessors' names, e.g. ID() instead of Id() for id field.")
+	flags.StringVar(&p.getterPrefix, "getter-prefix", "", "Prefix of getters' names, e.g. Get. Empty by default.")
+	flags.StringVar(&p.setterPrefix, "setter-prefix", "", "Prefix of setters' names, e.g. With. Defaults to Set.")
+	flags.StringVar(&p.haserPrefix, "haser-prefix", "", "Prefix of hasers' names, e.g. IsSet. Defaults to Has.")
+	flags.StringVar(&p.collisions, "collisions", "", "Strategy of resolving collisions of fields' accessors: error (default), suffix[:Affix] or prefix[:Affix]. Affix defaults to Field.")
+	flags.StringVar(&p.out, "out", "", "Where write to the generated command, - for the standard output.")
+	flags.StringVar(&p.sourcePkg, "source-pkg", "", "Package to load the struct from. Defaults to the package in the current working directory.")
+	flags.StringVar(&p.templates, "templates", "", "Directory with templates overriding the default ones.")
+	flags.StringVar(&p.config, "config", "", fmt.Sprintf("Config file listing commands to generate. Defaults to %s when no struct is given.", cmder.DefaultConfigFile))
+	flags.Var(p.exclude, "exclude", "Comma-separated selectors of struct fields to ignore when generating command, e.g. Foo, *At, re:^Foo, type:func or tag:json=-.")
+	flags.Var(p.include, "include", "Comma-separated selectors of struct fields to generate command from, like in -exclude. Overrides -exclude flag.")
+	flags.Var(p.embedded, "embedded", "Embedded field's path and mode: keep (default), flatten or skip, e.g. Base:flatten or Base.Audit:skip.")
+	flags.Var(p.rename, "rename", "Field's path and the name to use in its accessors, e.g. string:Str generates Str() and SetStr() for string field.")
+	flags.Var(p.nested, "nested", "Struct-typed field's path and optional name of a nested command to generate for it, e.g. Address or Address.Geo:GeoPatch.")
+	flags.Var(p.constructor, "constructor", `Constructor name, optional comma-separated list of fields and optional comma-separated flags.
+Use "default" as a constructor name to generate default constructor.
+Use * for all fields, optionally followed by fields to leave out prefixed with -.
+Flags: validate returns (Cmd, error) from the command's Validate() error method, ptr takes values for pointer fields.
+
+E.g.:
+-constructor default:foo,bar CreateStructCmd       // Generates NewCreateStructCmd(foo fooType, bar barType)
+-constructor WithFooAndBar:foo,bar CreateStructCmd // Generates NewCreateStructCmdWithFooAndBar(foo fooType, bar barType)
+-constructor All:*,-bar:validate CreateStructCmd   // Generates NewCreateStructCmdAll(foo fooType) (CreateStructCmd, error)`)
+
+	return p
 }
 
 type params struct {
 	mutable           bool
 	includeUnexported bool
 	sorted            bool
+	initialisms       bool
+	getterPrefix      string
+	setterPrefix      string
+	haserPrefix       string
+	collisions        string
 	out               string
+	sourcePkg         string
+	config            string
+	templates         string
 	exclude           *utils.UniqueMultiFlag[string]
 	include           *utils.UniqueMultiFlag[string]
-	constructor       *utils.UniqueMultiFlag[constructor]
-	structName        string
-	commandName       string
+	embedded          *utils.UniqueMultiFlag[embeddedField]
+	nested            *utils.UniqueMultiFlag[nestedCommand]
+	rename            *utils.UniqueMultiFlag[renamedField]
+	constructor       *utils.UniqueMultiFlag[cmder.Constructor]
+}
+
+// load builds the config from arguments: package patterns to discover directives in, struct and command names,
+// or none to use the config file.
+func (p *params) load(args []string) (*cmder.Config, error) {
+	config := &cmder.Config{}
+
+	switch {
+	case isPackagePatterns(args):
+		if p.config != "" {
+			return nil, errors.New("config file cannot be used together with package patterns")
+		}
+
+		config.Packages = args
+
+	case len(args) >= 2:
+		if p.config != "" {
+			return nil, errors.New("config file cannot be used together with struct and command name arguments")
+		}
+
+		config.Commands = append(config.Commands, p.command(args[0], args[1]))
+
+	case len(args) == 0:
+		if p.config == "" {
+			if _, err := os.Stat(cmder.DefaultConfigFile); err != nil {
+				return nil, errors.New("missing required arguments")
+			}
+
+			p.config = cmder.DefaultConfigFile
+		}
+
+		var err error
+
+		config, err = cmder.LoadConfig(p.config)
+		if err != nil {
+			return nil, fmt.Errorf("could not load config file: %w", err)
+		}
+
+	default:
+		return nil, errors.New("missing required arguments")
+	}
+
+	// Templates directory given as a flag is relative to the current working directory rather than the config file's one.
+	if p.templates != "" {
+		templates, err := filepath.Abs(p.templates)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Templates = templates
+	}
+
+	return config, nil
+}
+
+func (p *params) command(structName, commandName string) cmder.Command {
+	embedded := make(map[string]cmder.EmbeddedMode, p.embedded.Len())
+	for _, e := range p.embedded.Items() {
+		embedded[e.name] = e.mode
+	}
+
+	nested := make(map[string]string, p.nested.Len())
+	for _, n := range p.nested.Items() {
+		nested[n.path] = n.name
+	}
+
+	renames := make(map[string]string, p.rename.Len())
+	for _, r := range p.rename.Items() {
+		renames[r.path] = r.name
+	}
+
+	return cmder.Command{
+		Struct:            structName,
+		SourcePkg:         p.sourcePkg,
+		Name:              commandName,
+		Out:               p.out,
+		Mutable:           p.mutable,
+		IncludeUnexported: p.includeUnexported,
+		Sorted:            p.sorted,
+		Include:           p.include.Items(),
+		Exclude:           p.exclude.Items(),
+		Constructors:      p.constructor.Items(),
+		Embedded:          embedded,
+		Nested:            nested,
+		Renames:           renames,
+		Initialisms:       p.initialisms,
+		GetterPrefix:      p.getterPrefix,
+		SetterPrefix:      p.setterPrefix,
+		HaserPrefix:       p.haserPrefix,
+		Collisions:        p.collisions,
+	}
+}
+
+type embeddedField struct {
+	name string
+	mode cmder.EmbeddedMode
+}
+
+func (e embeddedField) UniqueValue() any {
+	return e.name
+}
+
+type nestedCommand struct {
+	path string
+	name string
+}
+
+func (n nestedCommand) UniqueValue() any {
+	return n.path
 }
 
-type constructor struct {
-	Name   string
-	Params []string
+type renamedField struct {
+	path string
+	name string
 }
 
-func (c constructor) UniqueValue() any {
-	return c.Name
+func (r renamedField) UniqueValue() any {
+	return r.path
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/donatorsky/go-cmder/cmder"
+)
+
+func TestInvocationReproducesCommand(t *testing.T) {
+	root := t.TempDir()
+
+	for path, content := range map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.20\n",
+		"domain/order.go": "package domain\n\ntype Order struct {\n\tID    string\n\tItems []string\n}\n",
+		"app/doc.go":      "package app\n",
+		"tpls/.keep":      "",
+	} {
+		path = filepath.Join(root, path)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	constructor, err := cmder.ParseConstructor("All:*,-Items")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cmder.Generate(context.Background(), cmder.Config{
+		Dir:       root,
+		Templates: "tpls",
+		Commands: []cmder.Command{{
+			Struct:       "Order",
+			SourcePkg:    "./domain",
+			Package:      "./app",
+			Name:         "OrderCmd",
+			Mutable:      true,
+			GetterPrefix: "Get",
+			Renames:      map[string]string{"ID": "Identifier"},
+			Constructors: []cmder.Constructor{constructor},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appDir := filepath.Join(root, "app")
+
+	generated, ok := files[filepath.Join(appDir, "order_cmd.go")]
+	if !ok {
+		t.Fatalf("order_cmd.go not generated, got %d files", len(files))
+	}
+
+	var invocation string
+
+	for _, line := range strings.Split(string(generated), "\n") {
+		if strings.HasPrefix(line, "// Invocation: go-cmder ") {
+			invocation = strings.TrimPrefix(line, "// Invocation: go-cmder ")
+
+			break
+		}
+	}
+
+	if invocation == "" {
+		t.Fatalf("invocation not recorded:\n%s", generated)
+	}
+
+	// The invocation is rerun from the command's package directory, like by go generate.
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(appDir); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		_ = os.Chdir(cwd)
+	}()
+
+	flags := flag.NewFlagSet("go-cmder", flag.ContinueOnError)
+	params := newParams(flags)
+
+	if err := flags.Parse(strings.Fields(invocation)); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := params.load(flags.Args())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regenerated, err := cmder.Generate(context.Background(), *config)
+	if err != nil {
+		t.Fatalf("rerunning %q: %v", invocation, err)
+	}
+
+	if len(regenerated) != 1 {
+		t.Fatalf("rerunning %q generated %d files, expected 1", invocation, len(regenerated))
+	}
+
+	for path, content := range regenerated {
+		if filepath.Base(path) != "order_cmd.go" {
+			t.Errorf("rerunning %q generated %s, expected order_cmd.go", invocation, path)
+		}
+
+		if string(content) != string(generated) {
+			t.Errorf("rerunning %q generated:\n%s\nexpected:\n%s", invocation, content, generated)
+		}
+	}
+}
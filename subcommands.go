@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/donatorsky/go-cmder/cmder"
+)
+
+type subcommand struct {
+	name        string
+	usage       string
+	description string
+	run         func(ctx context.Context, flags *flag.FlagSet, args []string) error
+}
+
+// subcommands lists available subcommands, the first one is the default.
+var subcommands = []*subcommand{
+	{
+		name: "generate",
+		usage: `go-cmder [generate] [flags] [path/to/pkg.]struct CommandName
+go-cmder [generate] [-config .cmder.yaml]
+go-cmder [generate] packages...`,
+		description: "Generate commands (default).",
+		run:         runGenerate(false),
+	},
+	{
+		name:        "check",
+		usage:       "go-cmder check [flags] [path/to/pkg.]struct CommandName | packages... | [-config .cmder.yaml]",
+		description: "Check that generated commands are up to date, print a diff when they are not.",
+		run:         runGenerate(true),
+	},
+	{
+		name:        "explain",
+		usage:       "go-cmder explain [flags] [path/to/pkg.]struct CommandName | packages... | [-config .cmder.yaml]",
+		description: "Print resolved types, imports and methods of commands without writing them.",
+		run:         runExplain,
+	},
+	{
+		name:        "list",
+		usage:       "go-cmder list [flags] [packages...]",
+		description: "List structs and their candidate fields.",
+		run:         runList,
+	},
+	{
+		name:        "clean",
+		usage:       "go-cmder clean [flags] [packages...]",
+		description: "Remove generated files.",
+		run:         runClean,
+	},
+}
+
+func findSubcommand(name string) *subcommand {
+	for _, subcommand := range subcommands {
+		if subcommand.name == name {
+			return subcommand
+		}
+	}
+
+	return nil
+}
+
+// runGenerate generates commands and writes them, or only checks them against the existing files.
+func runGenerate(check bool) func(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	return func(ctx context.Context, flags *flag.FlagSet, args []string) error {
+		params := newParams(flags)
+
+		if !check {
+			flags.BoolVar(&check, "check", false, "Whether to only check that the generated commands are up to date. Prints a diff and fails when they are not.")
+		}
+
+		if err := parseFlags(flags, args); err != nil {
+			return err
+		}
+
+		config, err := params.load(flags.Args())
+		if err != nil {
+			return err
+		}
+
+		files, err := cmder.Generate(ctx, *config)
+
+		if check {
+			return errors.Join(err, cmder.Check(files))
+		}
+
+		return errors.Join(err, cmder.Write(files, os.Stdout))
+	}
+}
+
+func runExplain(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	params := newParams(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	config, err := params.load(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	files, err := cmder.Generate(ctx, *config)
+
+	explanations, explainErr := cmder.Explain(files)
+
+	for i, explanation := range explanations {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		fmt.Printf("// %s\npackage %s\n", explanation.Path, explanation.Package)
+
+		if len(explanation.Imports) > 0 {
+			fmt.Printf("\nimport (\n\t%s\n)\n", strings.Join(explanation.Imports, "\n\t"))
+		}
+
+		for _, typeDecl := range explanation.Types {
+			fmt.Printf("\n%s\n", typeDecl)
+		}
+
+		if len(explanation.Funcs) > 0 {
+			fmt.Printf("\n%s\n", strings.Join(explanation.Funcs, "\n"))
+		}
+	}
+
+	return errors.Join(err, explainErr)
+}
+
+func runList(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	includeUnexported := flags.Bool("include-unexported", false, "Whether to list unexported fields.")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	structs, err := cmder.Structs(ctx, cmder.Config{Packages: flags.Args()})
+
+	for _, s := range structs {
+		fmt.Printf("%s.%s%s\t%s\n", s.PackagePath, s.Name, s.TypeParams, s.Position)
+
+		for _, field := range s.Fields {
+			if !field.Exported && !*includeUnexported {
+				continue
+			}
+
+			declaration := field.Type
+			if !field.Embedded {
+				declaration = field.Name + " " + declaration
+			}
+
+			if field.Tag != "" {
+				declaration += " `" + field.Tag + "`"
+			}
+
+			fmt.Printf("\t%s\n", declaration)
+		}
+	}
+
+	return err
+}
+
+func runClean(ctx context.Context, flags *flag.FlagSet, args []string) error {
+	dryRun := flags.Bool("dry-run", false, "Whether to only print generated files instead of removing them.")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	config := cmder.Config{Packages: flags.Args()}
+
+	clean := cmder.Clean
+	if *dryRun {
+		clean = cmder.GeneratedFiles
+	}
+
+	files, err := clean(ctx, config)
+
+	for _, file := range files {
+		fmt.Println(file)
+	}
+
+	return err
+}